@@ -0,0 +1,199 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const _clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+type AccessLogEntry struct {
+	Time       time.Time
+	RemoteAddr string
+	User       string
+	Method     string
+	URI        string
+	Proto      string
+	Host       string
+	Route      string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	Referer    string
+	UserAgent  string
+}
+
+// AccessLogFormatter renders an entry as a single line appended to buf, without the trailing newline.
+type AccessLogFormatter interface {
+	Format(buf []byte, e *AccessLogEntry) []byte
+}
+
+type AccessLogFormatterFunc func(buf []byte, e *AccessLogEntry) []byte
+
+func (f AccessLogFormatterFunc) Format(buf []byte, e *AccessLogEntry) []byte { return f(buf, e) }
+
+var (
+	CommonLogFormat   AccessLogFormatter = AccessLogFormatterFunc(formatCommon)
+	CombinedLogFormat AccessLogFormatter = AccessLogFormatterFunc(formatCombined)
+	JSONLogFormat     AccessLogFormatter = AccessLogFormatterFunc(formatJSON)
+)
+
+type accessLogConfig struct {
+	formatter AccessLogFormatter
+	filter    func(*http.Request) bool
+}
+
+// AccessLog writes one line per request to out, in the Apache combined format unless configured otherwise.
+func AccessLog(out io.Writer, opts ...AccessLogOption) Middleware {
+	cfg := accessLogConfig{formatter: CombinedLogFormat}
+	for _, opt := range opts {
+		opt.applyToAccessLog(&cfg)
+	}
+
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.filter != nil && !cfg.filter(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := WrapResponseWriter(w)
+			r = CaptureRoute(r)
+
+			next.ServeHTTP(rw, r)
+
+			e := newAccessLogEntry(r, rw, start)
+			line := append(cfg.formatter.Format(make([]byte, 0, 256), &e), '\n')
+
+			mu.Lock()
+			_, _ = out.Write(line)
+			mu.Unlock()
+		})
+	}
+}
+
+func newAccessLogEntry(r *http.Request, rw ResponseWriter, start time.Time) AccessLogEntry {
+	e := AccessLogEntry{
+		Time:       start,
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		URI:        r.RequestURI,
+		Proto:      r.Proto,
+		Host:       r.Host,
+		Route:      RoutePattern(r),
+		Status:     rw.Status(),
+		Bytes:      rw.BytesWritten(),
+		Duration:   time.Since(start),
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		e.RemoteAddr = host
+	}
+
+	if e.URI == "" {
+		e.URI = r.URL.RequestURI()
+	}
+
+	if user, _, ok := r.BasicAuth(); ok {
+		e.User = user
+	} else if r.URL.User != nil {
+		e.User = r.URL.User.Username()
+	}
+
+	return e
+}
+
+func formatCommon(buf []byte, e *AccessLogEntry) []byte {
+	buf = append(buf, orDash(e.RemoteAddr)...)
+	buf = append(buf, " - "...)
+	buf = append(buf, orDash(e.User)...)
+	buf = append(buf, " ["...)
+	buf = e.Time.AppendFormat(buf, _clfTimeFormat)
+	buf = append(buf, "] "...)
+	buf = strconv.AppendQuote(buf, e.Method+" "+e.URI+" "+e.Proto)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(e.Status), 10)
+	buf = append(buf, ' ')
+	if e.Bytes == 0 {
+		return append(buf, '-')
+	}
+	return strconv.AppendInt(buf, e.Bytes, 10)
+}
+
+func formatCombined(buf []byte, e *AccessLogEntry) []byte {
+	buf = formatCommon(buf, e)
+	buf = append(buf, ' ')
+	buf = strconv.AppendQuote(buf, orDash(e.Referer))
+	buf = append(buf, ' ')
+	return strconv.AppendQuote(buf, orDash(e.UserAgent))
+}
+
+func formatJSON(buf []byte, e *AccessLogEntry) []byte {
+	b, err := json.Marshal(struct {
+		Time       time.Time `json:"time"`
+		RemoteAddr string    `json:"remote_addr"`
+		User       string    `json:"user,omitempty"`
+		Method     string    `json:"method"`
+		URI        string    `json:"uri"`
+		Proto      string    `json:"proto"`
+		Host       string    `json:"host"`
+		Route      string    `json:"route,omitempty"`
+		Status     int       `json:"status"`
+		Bytes      int64     `json:"bytes"`
+		DurationMS float64   `json:"duration_ms"`
+		Referer    string    `json:"referer,omitempty"`
+		UserAgent  string    `json:"user_agent,omitempty"`
+	}{
+		Time:       e.Time,
+		RemoteAddr: e.RemoteAddr,
+		User:       e.User,
+		Method:     e.Method,
+		URI:        e.URI,
+		Proto:      e.Proto,
+		Host:       e.Host,
+		Route:      e.Route,
+		Status:     e.Status,
+		Bytes:      e.Bytes,
+		DurationMS: float64(e.Duration) / float64(time.Millisecond),
+		Referer:    e.Referer,
+		UserAgent:  e.UserAgent,
+	})
+	if err != nil {
+		return buf
+	}
+	return append(buf, b...)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type AccessLogOption interface {
+	applyToAccessLog(*accessLogConfig)
+}
+
+type AccessLogOptionFunc func(*accessLogConfig)
+
+func (f AccessLogOptionFunc) applyToAccessLog(c *accessLogConfig) { f(c) }
+
+func WithAccessLogFormatter(f AccessLogFormatter) AccessLogOptionFunc {
+	return func(c *accessLogConfig) { c.formatter = f }
+}
+
+// WithAccessLogFilter skips logging for requests for which fn returns false, e.g. health checks.
+func WithAccessLogFilter(fn func(*http.Request) bool) AccessLogOptionFunc {
+	return func(c *accessLogConfig) { c.filter = fn }
+}
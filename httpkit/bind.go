@@ -0,0 +1,228 @@
+package httpkit
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	_textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	_durationType        = reflect.TypeFor[time.Duration]()
+	_timeType            = reflect.TypeFor[time.Time]()
+)
+
+// Bind populates the struct pointed to by v from the request and validates it. The JSON body is decoded first with
+// DecodeJSON, then fields tagged `path:"name"`, `query:"name"` or `header:"Name"` are set from the path parameters,
+// the query string and the headers, and finally Validate runs. Values that cannot be parsed and failed validation
+// rules are reported together as a *ValidationError.
+func Bind(r *http.Request, v any, opts ...DecodeOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("httpkit: Bind requires a non-nil pointer to a struct, got %T", v)
+	}
+
+	if err := checkBindType(rv.Elem().Type()); err != nil {
+		return err
+	}
+
+	if r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody {
+		if err := DecodeJSON(r, v, opts...); err != nil {
+			return err
+		}
+	}
+
+	var verr ValidationError
+	bindRequest(rv.Elem(), r, &verr)
+
+	if len(verr.Fields) > 0 {
+		return &verr
+	}
+
+	return Validate(v)
+}
+
+func bindRequest(rv reflect.Value, r *http.Request, verr *ValidationError) {
+	rt := rv.Type()
+	query := r.URL.Query()
+
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		var (
+			name   string
+			values []string
+		)
+
+		switch {
+		case sf.Tag.Get("path") != "":
+			name = sf.Tag.Get("path")
+			if v := r.PathValue(name); v != "" {
+				values = []string{v}
+			}
+		case sf.Tag.Get("query") != "":
+			name = sf.Tag.Get("query")
+			values = query[name]
+		case sf.Tag.Get("header") != "":
+			name = sf.Tag.Get("header")
+			values = r.Header.Values(name)
+		default:
+			continue
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setField(rv.Field(i), values); err != nil {
+			verr.Add(name, err.Error())
+		}
+	}
+}
+
+func setField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 && !fv.Addr().Type().Implements(_textUnmarshalerType) {
+		s := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+
+	return setValue(fv, values[0])
+}
+
+func setValue(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setValue(fv.Elem(), s)
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(_textUnmarshalerType) {
+		if err := fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("is invalid")
+		}
+		return nil
+	}
+
+	switch fv.Type() {
+	case _durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("must be a duration")
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case _timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("must be an RFC 3339 timestamp")
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a non-negative integer")
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		fv.SetFloat(n)
+	default:
+		// Unreachable for types accepted by checkBindType.
+		return fmt.Errorf("cannot be bound")
+	}
+
+	return nil
+}
+
+// _bindTypes caches the result of checkBindType by struct type.
+var _bindTypes sync.Map
+
+// checkBindType reports fields of rt tagged for binding whose type cannot be parsed from a string, once per type, so
+// such mistakes fail every request the same way instead of only those setting the field.
+func checkBindType(rt reflect.Type) error {
+	if err, ok := _bindTypes.Load(rt); ok {
+		return typeError(err)
+	}
+
+	var err error
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("path") == "" && sf.Tag.Get("query") == "" && sf.Tag.Get("header") == "" {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(ft).Implements(_textUnmarshalerType) {
+			ft = ft.Elem()
+		}
+
+		if !bindable(ft) {
+			err = fmt.Errorf("httpkit: cannot bind into field %s.%s of type %s", rt, sf.Name, sf.Type)
+			break
+		}
+	}
+
+	_bindTypes.Store(rt, err)
+	return err
+}
+
+func bindable(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if reflect.PointerTo(t).Implements(_textUnmarshalerType) || t == _durationType || t == _timeType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// typeError converts a cached error, stored as a nil interface when the type is valid.
+func typeError(v any) error {
+	err, _ := v.(error)
+	return err
+}
@@ -0,0 +1,93 @@
+package httpkit
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// responseBuffer holds a response in memory so middlewares can inspect it before it is sent. Once the body grows
+// past limit, or the handler flushes, the buffered part is written out and the rest of the response is streamed.
+type responseBuffer struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	limit       int
+	passthrough bool
+}
+
+func newResponseBuffer(w http.ResponseWriter, limit int) *responseBuffer {
+	return &responseBuffer{w: w, header: make(http.Header), limit: limit}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	if b.passthrough {
+		return b.w.Header()
+	}
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.status != 0 {
+		return
+	}
+	b.status = code
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+
+	if b.passthrough {
+		return b.w.Write(p)
+	}
+
+	if b.limit > 0 && b.body.Len()+len(p) > b.limit {
+		if err := b.writeTo(b.w); err != nil {
+			return 0, err
+		}
+		return b.w.Write(p)
+	}
+
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) Flush() {
+	if !b.passthrough {
+		if err := b.writeTo(b.w); err != nil {
+			return
+		}
+	}
+	if f, ok := b.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (b *responseBuffer) Unwrap() http.ResponseWriter { return b.w }
+
+func (b *responseBuffer) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+// writeTo sends the buffered response to w and switches the buffer to passthrough mode.
+func (b *responseBuffer) writeTo(w http.ResponseWriter) error {
+	b.passthrough = true
+
+	copyHeader(w.Header(), b.header)
+	w.WriteHeader(b.statusCode())
+
+	_, err := w.Write(b.body.Bytes())
+	b.body.Reset()
+
+	return err
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, vv := range src {
+		dst[k] = append(dst[k][:0:0], vv...)
+	}
+}
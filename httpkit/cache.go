@@ -0,0 +1,330 @@
+package httpkit
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultCacheTTL          = 1 * time.Minute
+	_defaultCacheMaxEntrySize = 1 << 20
+	_defaultCacheMaxBytes     = 64 << 20
+)
+
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Stored time.Time
+}
+
+func (c *CachedResponse) size() int {
+	n := len(c.Body)
+	for k, vv := range c.Header {
+		n += len(k)
+		for _, v := range vv {
+			n += len(v)
+		}
+	}
+	return n
+}
+
+// CacheBackend stores cached responses. Implementations must be safe for concurrent use.
+type CacheBackend interface {
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+	Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type cacheConfig struct {
+	ttl          time.Duration
+	maxEntrySize int
+	varyHeaders  []string
+}
+
+// Cache serves GET and HEAD requests from backend, keyed by method, path, query and the configured vary headers.
+// Request and response Cache-Control directives are honored: no-store bypasses the cache, no-cache forces a refresh,
+// and max-age/s-maxage override the default TTL.
+func Cache(backend CacheBackend, opts ...CacheOption) Middleware {
+	cfg := cacheConfig{ttl: _defaultCacheTTL, maxEntrySize: _defaultCacheMaxEntrySize}
+	for _, opt := range opts {
+		opt.applyToCache(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+			if reqCC.has("no-store") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := cfg.key(r)
+
+			if !reqCC.has("no-cache") {
+				if resp, ok, err := backend.Get(r.Context(), key); err == nil && ok {
+					writeCached(w, r, resp)
+					return
+				}
+			}
+
+			buf := newResponseBuffer(w, cfg.maxEntrySize)
+			next.ServeHTTP(buf, r)
+
+			if buf.passthrough {
+				return
+			}
+
+			if ttl := cfg.storeTTL(r, buf); ttl > 0 {
+				resp := &CachedResponse{
+					Status: buf.statusCode(),
+					Header: buf.header.Clone(),
+					Body:   append([]byte(nil), buf.body.Bytes()...),
+					Stored: time.Now(),
+				}
+				_ = backend.Set(r.Context(), key, resp, ttl)
+			}
+
+			buf.header.Set("X-Cache", "MISS")
+			_ = buf.writeTo(w)
+		})
+	}
+}
+
+func (c *cacheConfig) key(r *http.Request) string {
+	var sb strings.Builder
+
+	sb.WriteString(r.Method)
+	sb.WriteByte(' ')
+	sb.WriteString(r.URL.Path)
+	if r.URL.RawQuery != "" {
+		sb.WriteByte('?')
+		sb.WriteString(r.URL.RawQuery)
+	}
+
+	for _, h := range c.varyHeaders {
+		sb.WriteByte('\n')
+		sb.WriteString(h)
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+
+	return sb.String()
+}
+
+// storeTTL returns how long the response to r may be stored, or 0 if it may not be stored, following the rules of
+// RFC 9111 for shared caches.
+func (c *cacheConfig) storeTTL(r *http.Request, buf *responseBuffer) time.Duration {
+	switch buf.statusCode() {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+	default:
+		return 0
+	}
+
+	if buf.header.Get("Set-Cookie") != "" || !c.coversVary(buf.header) {
+		return 0
+	}
+
+	cc := parseCacheControl(buf.header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return 0
+	}
+
+	// Responses to requests with credentials are personal unless explicitly marked otherwise (RFC 9111, 3.5).
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		if !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+			return 0
+		}
+	}
+
+	if age, ok := cc.seconds("s-maxage"); ok {
+		return age
+	}
+
+	if age, ok := cc.seconds("max-age"); ok {
+		return age
+	}
+
+	return c.ttl
+}
+
+// coversVary reports whether every request header the response varies on is part of the cache key, so the stored
+// response is only served to requests it fits.
+func (c *cacheConfig) coversVary(h http.Header) bool {
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if name == "*" || !slices.Contains(c.varyHeaders, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func writeCached(w http.ResponseWriter, r *http.Request, resp *CachedResponse) {
+	copyHeader(w.Header(), resp.Header)
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(resp.Stored).Seconds())))
+	w.Header().Set("X-Cache", "HIT")
+	w.WriteHeader(resp.Status)
+
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(s string) cacheControl {
+	cc := make(cacheControl)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	v, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+type CacheOption interface {
+	applyToCache(*cacheConfig)
+}
+
+type CacheOptionFunc func(*cacheConfig)
+
+func (f CacheOptionFunc) applyToCache(c *cacheConfig) { f(c) }
+
+func WithCacheTTL(d time.Duration) CacheOptionFunc {
+	return func(c *cacheConfig) { c.ttl = d }
+}
+
+// WithCacheMaxEntrySize sets the largest response body that is cached. Larger responses are streamed uncached.
+func WithCacheMaxEntrySize(n int) CacheOptionFunc {
+	return func(c *cacheConfig) { c.maxEntrySize = n }
+}
+
+// WithCacheVaryHeaders adds request headers to the cache key, e.g. Accept or Accept-Encoding. Responses with a Vary
+// header are only stored if all the headers it names are part of the key.
+func WithCacheVaryHeaders(headers ...string) CacheOptionFunc {
+	return func(c *cacheConfig) {
+		for _, h := range headers {
+			c.varyHeaders = append(c.varyHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+type memoryCacheEntry struct {
+	key     string
+	resp    *CachedResponse
+	expires time.Time
+	size    int
+}
+
+// MemoryCache is an in-process LRU CacheBackend bounded by the total size of the cached responses.
+type MemoryCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func NewMemoryCache(maxBytes int) *MemoryCache {
+	if maxBytes <= 0 {
+		maxBytes = _defaultCacheMaxBytes
+	}
+	return &MemoryCache{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.resp, true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	entry := &memoryCacheEntry{key: key, resp: resp, expires: time.Now().Add(ttl), size: resp.size()}
+	if entry.size > c.maxBytes {
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(entry)
+	c.bytes += entry.size
+
+	for c.bytes > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	entry := el.Value.(*memoryCacheEntry)
+	c.ll.Remove(el)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size
+}
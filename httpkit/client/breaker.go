@@ -0,0 +1,230 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultBreakerThreshold = 5
+	_defaultBreakerTimeout   = 30 * time.Second
+	_defaultBreakerProbes    = 1
+)
+
+// ErrCircuitOpen matches every *CircuitOpenError through errors.Is.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned without contacting the host while its circuit breaker is open.
+type CircuitOpenError struct {
+	Host string
+	// RetryAfter is how long until the breaker lets a probe request through.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open, retry after %s", e.Host, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through and counts consecutive failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails all requests until the open timeout has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probe requests through. Their success closes the breaker, a failure
+	// opens it again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+type breakerConfig struct {
+	threshold     int
+	timeout       time.Duration
+	probes        int
+	failure       func(req *http.Request, resp *http.Response, err error) bool
+	onStateChange func(host string, from, to BreakerState)
+}
+
+// CircuitBreaker keeps a breaker per host. After a number of consecutive failures it opens and fails requests to
+// that host with a *CircuitOpenError until the open timeout has passed, then lets probe requests decide whether to
+// close again. DefaultRetryPolicy does not retry these errors.
+func CircuitBreaker(opts ...BreakerOption) Middleware {
+	cfg := breakerConfig{
+		threshold: _defaultBreakerThreshold,
+		timeout:   _defaultBreakerTimeout,
+		probes:    _defaultBreakerProbes,
+		failure:   breakerFailure,
+	}
+
+	for _, opt := range opts {
+		opt.applyToBreaker(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		var (
+			mu       sync.Mutex
+			breakers = make(map[string]*breaker)
+		)
+
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+
+			mu.Lock()
+			b, ok := breakers[host]
+			if !ok {
+				b = &breaker{cfg: &cfg, host: host}
+				breakers[host] = b
+			}
+			mu.Unlock()
+
+			gen, err := b.allow()
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(req)
+			b.record(gen, cfg.failure(req, resp, err))
+
+			return resp, err
+		})
+	}
+}
+
+// breakerFailure counts transport errors, except for requests canceled by their caller, and 5xx responses.
+func breakerFailure(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type breaker struct {
+	cfg  *breakerConfig
+	host string
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+	// generation changes with every state change, so results of requests started in an earlier state are ignored.
+	generation uint64
+}
+
+func (b *breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if wait := b.cfg.timeout - time.Since(b.openedAt); wait > 0 {
+			return 0, &CircuitOpenError{Host: b.host, RetryAfter: wait}
+		}
+		b.transition(BreakerHalfOpen)
+	}
+
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.cfg.probes {
+			return 0, &CircuitOpenError{Host: b.host}
+		}
+		b.probes++
+	}
+
+	return b.generation, nil
+}
+
+func (b *breaker) record(gen uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen != b.generation {
+		return
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		if b.failures++; b.failures >= b.cfg.threshold {
+			b.transition(BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.transition(BreakerOpen)
+			return
+		}
+		if b.successes++; b.successes >= b.cfg.probes {
+			b.transition(BreakerClosed)
+		}
+	}
+}
+
+func (b *breaker) transition(to BreakerState) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures, b.probes, b.successes = 0, 0, 0
+	if to == BreakerOpen {
+		b.openedAt = time.Now()
+	}
+
+	if b.cfg.onStateChange != nil {
+		b.cfg.onStateChange(b.host, from, to)
+	}
+}
+
+type BreakerOption interface {
+	applyToBreaker(*breakerConfig)
+}
+
+type BreakerOptionFunc func(*breakerConfig)
+
+func (f BreakerOptionFunc) applyToBreaker(c *breakerConfig) { f(c) }
+
+// WithBreakerThreshold sets how many consecutive failures open the breaker. The default is 5.
+func WithBreakerThreshold(n int) BreakerOptionFunc {
+	return func(c *breakerConfig) { c.threshold = max(n, 1) }
+}
+
+// WithBreakerTimeout sets how long the breaker stays open before probing the host. The default is 30 seconds.
+func WithBreakerTimeout(d time.Duration) BreakerOptionFunc {
+	return func(c *breakerConfig) { c.timeout = d }
+}
+
+// WithBreakerProbes sets how many probe requests are let through while half-open, all of which must succeed to close
+// the breaker. The default is 1.
+func WithBreakerProbes(n int) BreakerOptionFunc {
+	return func(c *breakerConfig) { c.probes = max(n, 1) }
+}
+
+// WithBreakerFailure decides which results count as failures. By default these are transport errors and 5xx
+// responses.
+func WithBreakerFailure(fn func(req *http.Request, resp *http.Response, err error) bool) BreakerOptionFunc {
+	return func(c *breakerConfig) { c.failure = fn }
+}
+
+// WithBreakerStateChange calls fn whenever the breaker of a host changes its state. It is called with the breaker
+// locked and must not block.
+func WithBreakerStateChange(fn func(host string, from, to BreakerState)) BreakerOptionFunc {
+	return func(c *breakerConfig) { c.onStateChange = fn }
+}
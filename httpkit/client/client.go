@@ -0,0 +1,115 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	_defaultTimeout    = 30 * time.Second
+	_defaultMaxRetries = 2
+	_defaultBaseDelay  = 100 * time.Millisecond
+	_defaultMaxDelay   = 5 * time.Second
+)
+
+type config struct {
+	timeout        time.Duration
+	attemptTimeout time.Duration
+	maxRetries     int
+	backoff        Backoff
+	policy         RetryPolicy
+	maxRetryAfter  time.Duration
+	maxBody        int64
+	transport      http.RoundTripper
+	middlewares    []Middleware
+}
+
+// Client is an *http.Client whose transport retries failed requests according to its retry policy.
+type Client struct {
+	*http.Client
+}
+
+func New(opts ...Option) *Client {
+	cfg := config{
+		timeout:       _defaultTimeout,
+		maxRetries:    _defaultMaxRetries,
+		backoff:       ExponentialBackoff(_defaultBaseDelay, _defaultMaxDelay),
+		policy:        DefaultRetryPolicy,
+		maxRetryAfter: _defaultMaxDelay,
+		transport:     http.DefaultTransport,
+	}
+
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	return &Client{
+		Client: &http.Client{
+			Transport: &RetryTransport{
+				Next:            Chain(cfg.middlewares...)(cfg.transport),
+				MaxRetries:      cfg.maxRetries,
+				Backoff:         cfg.backoff,
+				Policy:          cfg.policy,
+				AttemptTimeout:  cfg.attemptTimeout,
+				MaxRetryAfter:   cfg.maxRetryAfter,
+				MaxBufferedBody: cfg.maxBody,
+			},
+			Timeout: cfg.timeout,
+		},
+	}
+}
+
+type Option interface {
+	applyToConfig(*config)
+}
+
+type OptionFunc func(*config)
+
+func (f OptionFunc) applyToConfig(c *config) { f(c) }
+
+// WithTimeout limits the whole request including all retries. The default is 30 seconds; zero means no limit.
+func WithTimeout(d time.Duration) OptionFunc {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithAttemptTimeout limits every single attempt, so a hanging upstream is retried instead of using up the whole
+// timeout.
+func WithAttemptTimeout(d time.Duration) OptionFunc {
+	return func(c *config) { c.attemptTimeout = d }
+}
+
+// WithMaxRetries sets how often a request is retried after the first attempt. The default is 2; zero disables
+// retries.
+func WithMaxRetries(n int) OptionFunc {
+	return func(c *config) { c.maxRetries = n }
+}
+
+func WithBackoff(b Backoff) OptionFunc {
+	return func(c *config) { c.backoff = b }
+}
+
+func WithRetryPolicy(p RetryPolicy) OptionFunc {
+	return func(c *config) { c.policy = p }
+}
+
+// WithMaxRetryAfter caps how long a Retry-After header may delay the next attempt. Longer delays are not retried.
+func WithMaxRetryAfter(d time.Duration) OptionFunc {
+	return func(c *config) { c.maxRetryAfter = d }
+}
+
+// WithMaxBufferedBody caps the size of request bodies buffered to be sent again on retries. Requests with larger
+// bodies that cannot recreate them through GetBody are not retried. The default is 1 MiB.
+func WithMaxBufferedBody(n int64) OptionFunc {
+	return func(c *config) { c.maxBody = n }
+}
+
+// WithTransport sets the transport that performs the attempts. The default is http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) OptionFunc {
+	return func(c *config) { c.transport = rt }
+}
+
+// WithMiddleware wraps the transport with mws, the first being the outermost. They run for every attempt, so headers
+// are set anew and each retry is logged, traced and measured on its own.
+func WithMiddleware(mws ...Middleware) OptionFunc {
+	return func(c *config) { c.middlewares = append(c.middlewares, mws...) }
+}
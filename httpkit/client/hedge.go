@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Hedger sends a duplicate of a request that has not been answered after a delay and uses whichever response
+// arrives first, cutting tail latency at the cost of extra load. Only idempotent requests whose body can be
+// recreated are hedged; the losing attempts are canceled.
+type Hedger struct {
+	delay     time.Duration
+	maxHedges int
+
+	requests atomic.Int64
+	hedges   atomic.Int64
+	wins     atomic.Int64
+}
+
+// HedgeStats counts the requests seen by a Hedger, the hedge requests it sent and how often a hedge request won.
+type HedgeStats struct {
+	Requests int64
+	Hedges   int64
+	Wins     int64
+}
+
+// NewHedger hedges requests not answered within delay, which is best set around the p95 latency of the upstream.
+func NewHedger(delay time.Duration, opts ...HedgeOption) *Hedger {
+	h := Hedger{delay: delay, maxHedges: 1}
+	for _, opt := range opts {
+		opt.applyToHedger(&h)
+	}
+	return &h
+}
+
+func (h *Hedger) Stats() HedgeStats {
+	return HedgeStats{Requests: h.requests.Load(), Hedges: h.hedges.Load(), Wins: h.wins.Load()}
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+func (h *Hedger) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		hasBody := req.Body != nil && req.Body != http.NoBody
+		if !Idempotent(req) || (hasBody && req.GetBody == nil) {
+			return next.RoundTrip(req)
+		}
+
+		h.requests.Add(1)
+
+		results := make(chan hedgeResult, h.maxHedges+1)
+		cancels := make([]context.CancelFunc, 0, h.maxHedges+1)
+
+		launch := func(attempt int) {
+			ctx, cancel := context.WithCancel(req.Context())
+			cancels = append(cancels, cancel)
+
+			areq := req.WithContext(ctx)
+			if attempt > 0 && hasBody {
+				body, err := req.GetBody()
+				if err != nil {
+					results <- hedgeResult{attempt: attempt, err: err}
+					return
+				}
+				areq.Body = body
+			}
+
+			go func() {
+				resp, err := next.RoundTrip(areq)
+				results <- hedgeResult{attempt: attempt, resp: resp, err: err}
+			}()
+		}
+
+		timer := time.NewTimer(h.delay)
+		defer timer.Stop()
+
+		launch(0)
+		launched, pending := 1, 1
+
+		var last hedgeResult
+		for {
+			select {
+			case res := <-results:
+				pending--
+				if res.err == nil {
+					h.settle(res, results, cancels, pending)
+					return res.resp, nil
+				}
+
+				cancels[res.attempt]()
+				last = res
+				if pending > 0 {
+					continue
+				}
+				if launched > h.maxHedges {
+					return nil, last.err
+				}
+
+				// A failed attempt is hedged after the delay like a slow one, so an upstream that fails fast does not
+				// receive all hedges at once.
+				select {
+				case <-req.Context().Done():
+					return nil, last.err
+				case <-timer.C:
+				}
+			case <-timer.C:
+				if launched > h.maxHedges {
+					continue
+				}
+			}
+
+			h.hedges.Add(1)
+			launch(launched)
+			launched++
+			pending++
+			timer.Reset(h.delay)
+		}
+	})
+}
+
+// settle hands the winning response to the caller, keeping its attempt alive until the body is closed. The other
+// attempts are canceled and responses still arriving from them are closed in the background.
+func (h *Hedger) settle(win hedgeResult, results <-chan hedgeResult, cancels []context.CancelFunc, pending int) {
+	if win.attempt > 0 {
+		h.wins.Add(1)
+	}
+
+	for i, cancel := range cancels {
+		if i != win.attempt {
+			cancel()
+		}
+	}
+
+	win.resp.Body = &cancelBody{ReadCloser: win.resp.Body, cancel: cancels[win.attempt]}
+
+	if pending == 0 {
+		return
+	}
+
+	go func() {
+		for range pending {
+			if res := <-results; res.resp != nil {
+				res.resp.Body.Close()
+			}
+		}
+	}()
+}
+
+type HedgeOption interface {
+	applyToHedger(*Hedger)
+}
+
+type HedgeOptionFunc func(*Hedger)
+
+func (f HedgeOptionFunc) applyToHedger(h *Hedger) { f(h) }
+
+// WithMaxHedges sets how many duplicates are sent at most, one delay apart. The default is 1.
+func WithMaxHedges(n int) HedgeOptionFunc {
+	return func(h *Hedger) { h.maxHedges = max(n, 1) }
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const _maxErrorBodyBytes = 64 << 10
+
+// Request builds an outbound request step by step. Errors are kept until Build or Do, so calls can be chained.
+type Request struct {
+	client *Client
+	ctx    context.Context
+	method string
+	url    string
+	params map[string]string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+	err    error
+}
+
+// NewRequest starts a request to urlTemplate, whose {name} placeholders are filled by Param.
+func (c *Client) NewRequest(method, urlTemplate string) *Request {
+	return &Request{
+		client: c,
+		ctx:    context.Background(),
+		method: method,
+		url:    urlTemplate,
+		params: make(map[string]string),
+		query:  make(url.Values),
+		header: make(http.Header),
+	}
+}
+
+func (c *Client) Get(urlTemplate string) *Request {
+	return c.NewRequest(http.MethodGet, urlTemplate)
+}
+
+func (c *Client) Post(urlTemplate string) *Request {
+	return c.NewRequest(http.MethodPost, urlTemplate)
+}
+
+func (c *Client) Put(urlTemplate string) *Request {
+	return c.NewRequest(http.MethodPut, urlTemplate)
+}
+
+func (c *Client) Patch(urlTemplate string) *Request {
+	return c.NewRequest(http.MethodPatch, urlTemplate)
+}
+
+func (c *Client) Delete(urlTemplate string) *Request {
+	return c.NewRequest(http.MethodDelete, urlTemplate)
+}
+
+func (r *Request) Context(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Param replaces the {name} placeholder in the URL with the path-escaped value.
+func (r *Request) Param(name, value string) *Request {
+	r.params[name] = value
+	return r
+}
+
+// Query adds a query parameter, keeping any already present in the URL.
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Body sends body with the given content type.
+func (r *Request) Body(body io.Reader, contentType string) *Request {
+	r.body = body
+	r.header.Set("Content-Type", contentType)
+	return r
+}
+
+// JSON sends v encoded as JSON.
+func (r *Request) JSON(v any) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = fmt.Errorf("encoding request body: %w", err)
+		return r
+	}
+
+	r.header.Set("Accept", "application/json")
+	return r.Body(bytes.NewReader(b), "application/json")
+}
+
+func (r *Request) Build() (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	raw := r.url
+	for name, value := range r.params {
+		raw = strings.ReplaceAll(raw, "{"+name+"}", url.PathEscape(value))
+	}
+	if i := strings.IndexByte(raw, '{'); i >= 0 && strings.IndexByte(raw[i:], '}') > 0 {
+		return nil, fmt.Errorf("missing parameter in URL %q", raw)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.query) > 0 {
+		q := u.Query()
+		for k, vs := range r.query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, r.method, u.String(), r.body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, vs := range r.header {
+		req.Header[k] = vs
+	}
+
+	return req, nil
+}
+
+// Do sends the request. Responses with a status outside 2xx are returned as a *StatusError holding the start of the
+// response body, and their body is closed.
+func (r *Request) Do() (*Response, error) {
+	req, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, _maxErrorBodyBytes))
+		return nil, &StatusError{
+			Method:     req.Method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       body,
+		}
+	}
+
+	return &Response{Response: resp}, nil
+}
+
+// DecodeJSON sends the request and decodes the JSON response into v.
+func (r *Request) DecodeJSON(v any) error {
+	resp, err := r.Do()
+	if err != nil {
+		return err
+	}
+	return resp.DecodeJSON(v)
+}
+
+type Response struct {
+	*http.Response
+}
+
+// DecodeJSON decodes the response body into v and closes it.
+func (r *Response) DecodeJSON(v any) error {
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// Discard reads the rest of the body and closes it, so the connection can be reused.
+func (r *Response) Discard() error {
+	_, err := io.Copy(io.Discard, r.Body)
+	return errors.Join(err, r.Body.Close())
+}
+
+// StatusError is returned by Request.Do for responses with a status code outside 2xx.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	// Body holds up to 64 KiB of the response body.
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy reports whether a request should be retried after an attempt ended with resp or err.
+type RetryPolicy func(req *http.Request, resp *http.Response, err error) bool
+
+const _defaultMaxBufferedBody = 1 << 20
+
+// Backoff returns how long to wait before the given retry, starting at 1.
+type Backoff func(retry int) time.Duration
+
+// DefaultRetryPolicy retries idempotent requests that failed with a transport error or a 429, 502, 503 or 504
+// response. Requests canceled by their context and requests failed by an open circuit breaker are never retried.
+func DefaultRetryPolicy(req *http.Request, resp *http.Response, err error) bool {
+	return Idempotent(req) && RetryableFailure(req, resp, err)
+}
+
+// RetryableFailure is DefaultRetryPolicy without the method check, for APIs with idempotency keys.
+func RetryableFailure(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && !errors.Is(err, ErrCircuitOpen)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func Idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExponentialBackoff doubles the delay with every retry up to maxDelay and picks a random delay up to that value ("full
+// jitter"), so clients failing together do not retry together.
+func ExponentialBackoff(base, maxDelay time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		d := base << (retry - 1)
+		if d <= 0 || d > maxDelay {
+			d = maxDelay
+		}
+		return rand.N(max(d, 1)) + 1
+	}
+}
+
+// RetryTransport retries requests according to Policy. Request bodies are buffered on the first attempt when the
+// request cannot recreate them through GetBody; requests with bodies larger than MaxBufferedBody are sent once.
+type RetryTransport struct {
+	Next           http.RoundTripper
+	MaxRetries     int
+	Backoff        Backoff
+	Policy         RetryPolicy
+	AttemptTimeout time.Duration
+	// MaxRetryAfter caps the delay requested by a Retry-After header; responses asking for longer are returned.
+	MaxRetryAfter time.Duration
+	// MaxBufferedBody caps the size of a request body buffered for retries. Zero means 1 MiB.
+	MaxBufferedBody int64
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxRetries := t.MaxRetries
+	if maxRetries > 0 {
+		maxBody := t.MaxBufferedBody
+		if maxBody <= 0 {
+			maxBody = _defaultMaxBufferedBody
+		}
+
+		var (
+			ok  bool
+			err error
+		)
+		if req, ok, err = rewindable(req, maxBody); err != nil {
+			return nil, err
+		}
+		if !ok {
+			maxRetries = 0
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		areq := req
+		if attempt > 0 {
+			areq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				areq.Body = body
+			}
+		}
+
+		resp, err := t.attempt(areq)
+		if attempt == maxRetries || !t.Policy(areq, resp, err) {
+			return resp, err
+		}
+
+		delay := t.Backoff(attempt + 1)
+		if resp != nil {
+			if ra, ok := retryAfter(resp); ok {
+				if t.MaxRetryAfter > 0 && ra > t.MaxRetryAfter {
+					return resp, nil
+				}
+				delay = ra
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *RetryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.AttemptTimeout <= 0 {
+		return t.Next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.AttemptTimeout)
+
+	resp, err := t.Next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The attempt context must live until the body is read, so it is canceled when the body is closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// rewindable returns a request whose body can be sent again, buffering the body into a copy of req if it cannot be
+// recreated through GetBody. Bodies larger than maxBody are not buffered and rewindable reports false; the returned
+// request then sends the body once. The caller's request is left untouched as RoundTrippers must not modify it.
+func rewindable(req *http.Request, maxBody int64) (*http.Request, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, true, nil
+	}
+	if req.ContentLength > maxBody {
+		return req, false, nil
+	}
+
+	b, err := io.ReadAll(io.LimitReader(req.Body, maxBody+1))
+	if err != nil {
+		req.Body.Close()
+		return nil, false, err
+	}
+
+	req = req.Clone(req.Context())
+
+	if int64(len(b)) > maxBody {
+		// The part already read is sent ahead of the rest.
+		req.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(b), req.Body), Closer: req.Body}
+		return req, false, nil
+	}
+
+	req.Body.Close()
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	req.Body, _ = req.GetBody()
+
+	return req, true, nil
+}
+
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// retryAfter parses the Retry-After header given in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RoundTripperFunc adapts a function to http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Middleware wraps a transport the way server middleware wraps a handler. Like any RoundTripper, the returned
+// transport must not modify the request it is given; clone it first.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// Chain composes middlewares so that the first one is the outermost.
+func Chain(mws ...Middleware) Middleware {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		for i := len(mws) - 1; i >= 0; i-- {
+			rt = mws[i](rt)
+		}
+		return rt
+	}
+}
+
+// SetHeader sets a header on every request that does not already carry it.
+func SetHeader(key, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(key) != "" {
+				return next.RoundTrip(req)
+			}
+
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func UserAgent(ua string) Middleware {
+	return SetHeader("User-Agent", ua)
+}
+
+// BearerToken sets the Authorization header from token, which is called for every attempt so expiring tokens can be
+// refreshed between retries.
+func BearerToken(token func(ctx context.Context) (string, error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			t, err := token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+t)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Logging logs every request with its status and duration, at error level when the transport failed.
+func Logging(log *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Duration("duration", time.Since(start)),
+			}
+
+			if err != nil {
+				attrs = append(attrs, slog.Group("error", slog.String("msg", err.Error())))
+				log.LogAttrs(req.Context(), slog.LevelError, "outbound request", attrs...)
+				return nil, err
+			}
+
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			log.LogAttrs(req.Context(), slog.LevelInfo, "outbound request", attrs...)
+
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type coalesceConfig struct {
+	key func(*http.Request) string
+}
+
+// Coalesce collapses identical concurrent GET and HEAD requests into a single execution of next and sends the
+// buffered response to every waiting client. The shared execution is detached from the leading request's
+// cancellation so one client going away does not fail the others. Set-Cookie is removed from shared responses, and
+// responses with a Vary header are only used for the request that produced them.
+func Coalesce(opts ...CoalesceOption) Middleware {
+	cfg := coalesceConfig{key: defaultCoalesceKey}
+	for _, opt := range opts {
+		opt.applyToCoalesce(&cfg)
+	}
+
+	var group singleflight.Group
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := cfg.key(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			v, _, shared := group.Do(key, func() (any, error) {
+				buf := newResponseBuffer(discardWriter{}, 0)
+				next.ServeHTTP(struct{ http.ResponseWriter }{buf}, r.WithContext(context.WithoutCancel(r.Context())))
+				return &coalescedResponse{
+					leader: r,
+					CachedResponse: CachedResponse{
+						Status: buf.statusCode(),
+						Header: buf.header,
+						Body:   buf.body.Bytes(),
+						Stored: time.Now(),
+					},
+				}, nil
+			})
+
+			resp := v.(*coalescedResponse)
+
+			// The key does not cover the request headers the response varies on, so followers cannot be sure the
+			// response fits their request and are served on their own.
+			if shared && resp.leader != r && resp.Header.Get("Vary") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			copyHeader(w.Header(), resp.Header)
+			if shared {
+				// A cookie set for one client must not reach the others.
+				w.Header().Del("Set-Cookie")
+				w.Header().Set("X-Coalesced", "true")
+			}
+			w.WriteHeader(resp.Status)
+
+			if r.Method != http.MethodHead {
+				_, _ = w.Write(resp.Body)
+			}
+		})
+	}
+}
+
+// defaultCoalesceKey groups requests by method and URL. Requests carrying credentials are not coalesced, as their
+// responses may be personal.
+func defaultCoalesceKey(r *http.Request) string {
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return ""
+	}
+	return r.Method + " " + r.Host + r.URL.RequestURI()
+}
+
+type coalescedResponse struct {
+	CachedResponse
+	leader *http.Request
+}
+
+// discardWriter is the target of a responseBuffer that is shared between requests and must never write through.
+type discardWriter struct{}
+
+func (discardWriter) Header() http.Header         { return make(http.Header) }
+func (discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (discardWriter) WriteHeader(int)             {}
+
+type CoalesceOption interface {
+	applyToCoalesce(*coalesceConfig)
+}
+
+type CoalesceOptionFunc func(*coalesceConfig)
+
+func (f CoalesceOptionFunc) applyToCoalesce(c *coalesceConfig) { f(c) }
+
+// WithCoalesceKey sets the function grouping requests. Requests for which it returns "" are never coalesced. The key
+// must include the identity of the caller, e.g. the authenticated user, for any response that depends on it, or one
+// user's response is served to another; the default key does not coalesce requests with credentials at all.
+func WithCoalesceKey(fn func(*http.Request) string) CoalesceOptionFunc {
+	return func(c *coalesceConfig) { c.key = fn }
+}
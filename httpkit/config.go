@@ -1,13 +1,13 @@
 package httpkit
 
 import (
+	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
 	"log"
 	"net"
-	"os"
+	"net/http"
 	"strconv"
 	"time"
 )
@@ -27,9 +27,29 @@ type Config struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
-	ErrorLog        *log.Logger
-	TLS             *tls.Config
-	tlsErr          error
+	// ExtraAddrs are further host:port addresses served by the same handler, e.g. an IPv6 address next to an IPv4
+	// Host, or a localhost-only port for admin access.
+	ExtraAddrs []string
+	// DrainDelay is how long the server keeps serving after shutdown begins, so load balancers can observe the
+	// failing readiness check before the listener closes.
+	DrainDelay  time.Duration
+	ErrorLog    *log.Logger
+	TLS         *tls.Config
+	loadTLS     func() (*tls.Config, error)
+	tlsPolicy   tlsPolicy
+	onShutdown  []func()
+	onDrain     []func()
+	afterStop   []func(context.Context) error
+	drainReport func(inFlight int64)
+	restart     bool
+	reusePort   bool
+	drainSignal bool
+	redirect    string
+	connState   []func(net.Conn, http.ConnState)
+	grpc        GRPCServer
+	grpcCalls   *grpcCalls
+	warmup      []func(context.Context) error
+	acme        func(http.Handler) http.Handler
 }
 
 func DefaultConfig() Config {
@@ -44,6 +64,18 @@ func DefaultConfig() Config {
 
 func (c Config) Addr() string { return net.JoinHostPort(c.Host, strconv.Itoa(c.Port)) }
 
+// Addrs returns Addr followed by ExtraAddrs.
+func (c Config) Addrs() []string { return append([]string{c.Addr()}, c.ExtraAddrs...) }
+
+// listenAddrs are the addresses Serve listens on: Addrs, followed by the address of the HTTP redirect if set.
+func (c Config) listenAddrs() []string {
+	addrs := c.Addrs()
+	if c.redirect != "" {
+		addrs = append(addrs, c.redirect)
+	}
+	return addrs
+}
+
 func (c *Config) Override(other Config) {
 	if other.Host != "" {
 		c.Host = other.Host
@@ -53,6 +85,10 @@ func (c *Config) Override(other Config) {
 		c.Port = other.Port
 	}
 
+	if len(other.ExtraAddrs) > 0 {
+		c.ExtraAddrs = other.ExtraAddrs
+	}
+
 	if other.IdleTimeout != 0 {
 		c.IdleTimeout = other.IdleTimeout
 	}
@@ -68,6 +104,10 @@ func (c *Config) Override(other Config) {
 	if other.ShutdownTimeout != 0 {
 		c.ShutdownTimeout = other.ShutdownTimeout
 	}
+
+	if other.DrainDelay != 0 {
+		c.DrainDelay = other.DrainDelay
+	}
 }
 
 func (c *Config) Validate() error {
@@ -77,6 +117,12 @@ func (c *Config) Validate() error {
 		return errors.New("port must be greater than 0")
 	}
 
+	for _, addr := range c.ExtraAddrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("extra address %q must be host:port: %w", addr, err)
+		}
+	}
+
 	if c.IdleTimeout <= 0 {
 		return errors.New("idle timeout must be greater than 0")
 	}
@@ -93,8 +139,30 @@ func (c *Config) Validate() error {
 		return errors.New("shutdown timeout must be greater than 0")
 	}
 
-	if c.tlsErr != nil {
-		return fmt.Errorf("tls must be configured correctly if provided: %w", c.tlsErr)
+	if c.DrainDelay < 0 {
+		return errors.New("drain delay must not be negative")
+	}
+
+	if c.redirect != "" && c.loadTLS == nil && c.TLS == nil {
+		return errors.New("http redirect requires tls to be configured")
+	}
+
+	if c.loadTLS != nil {
+		tlsConfig, err := c.loadTLS()
+		if err != nil {
+			return fmt.Errorf("tls must be configured correctly if provided: %w", err)
+		}
+		c.TLS, c.loadTLS = tlsConfig, nil
+	}
+
+	// The policy applies to a TLS config set directly or through WithConfig as well. It is applied to a copy, as the
+	// config may be shared with other servers.
+	if c.TLS != nil {
+		tlsConfig := c.TLS.Clone()
+		if err := c.tlsPolicy.apply(tlsConfig); err != nil {
+			return fmt.Errorf("tls must be configured correctly if provided: %w", err)
+		}
+		c.TLS = tlsConfig
 	}
 
 	return nil
@@ -127,14 +195,31 @@ type ConfigOption interface{ applyToConfig(*Config) }
 type (
 	hostOption            struct{ value string }
 	portOption            struct{ value int }
+	extraAddrsOption      struct{ value []string }
 	idleTimeoutOption     struct{ value time.Duration }
 	readTimeoutOption     struct{ value time.Duration }
 	writeTimeoutOption    struct{ value time.Duration }
 	shutdownTimeoutOption struct{ value time.Duration }
+	drainDelayOption      struct{ value time.Duration }
+	onShutdownOption      struct{ value func() }
+	onDrainOption         struct{ value func() }
+	drainReporterOption   struct{ value func(int64) }
+	gracefulRestartOption struct{}
+	reusePortOption       struct{}
+	drainSignalOption     struct{}
+	tlsOption             struct{ value func() (*tls.Config, error) }
+	httpRedirectOption    struct{ value string }
+
+	connStateOption struct {
+		value func(net.Conn, http.ConnState)
+	}
 
-	tlsOption struct {
-		value *tls.Config
-		err   error
+	afterShutdownOption struct {
+		value func(context.Context) error
+	}
+
+	acmeOption struct {
+		value func(http.Handler) http.Handler
 	}
 
 	configOption  struct{ value Config }
@@ -143,50 +228,94 @@ type (
 
 func WithHost(v string) ConfigOption                   { return hostOption{value: v} }
 func WithPort(v int) ConfigOption                      { return portOption{value: v} }
+func WithExtraAddrs(v ...string) ConfigOption          { return extraAddrsOption{value: v} }
 func WithIdleTimeout(v time.Duration) ConfigOption     { return idleTimeoutOption{value: v} }
 func WithReadTimeout(v time.Duration) ConfigOption     { return readTimeoutOption{value: v} }
 func WithWriteTimeout(v time.Duration) ConfigOption    { return writeTimeoutOption{value: v} }
 func WithShutdownTimeout(v time.Duration) ConfigOption { return shutdownTimeoutOption{value: v} }
-func WithConfig(v Config) ConfigOption                 { return configOption{value: v} }
-func WithConfigOptions(v ...ConfigOption) ConfigOption { return configOptions{value: v} }
+func WithDrainDelay(v time.Duration) ConfigOption      { return drainDelayOption{value: v} }
+func WithOnShutdown(v func()) ConfigOption             { return onShutdownOption{value: v} }
 
-func WithTLS(caFile, ceFile, keyFile string) ConfigOption {
-	ce, err := tls.LoadX509KeyPair(ceFile, keyFile)
-	if err != nil {
-		return tlsOption{err: err}
-	}
+// WithGracefulRestart makes the server start a new instance of its executable on SIGUSR2, handing over the
+// listening socket, and then shut down gracefully, so binaries can be upgraded without refusing connections.
+func WithGracefulRestart() ConfigOption { return gracefulRestartOption{} }
 
-	ca, err := os.ReadFile(caFile)
-	if err != nil {
-		return tlsOption{err: err}
-	}
+// WithReusePort sets SO_REUSEPORT on the listener so a new instance can bind the same address while the old one
+// drains.
+func WithReusePort() ConfigOption { return reusePortOption{} }
 
-	pool := x509.NewCertPool()
-	if ok := pool.AppendCertsFromPEM(ca); !ok {
-		return tlsOption{err: errors.New("unable to append certs from PEM")}
-	}
+// WithDrainSignal lets handlers observe through Draining that shutdown has begun, well before their contexts are
+// canceled when the shutdown timeout expires.
+func WithDrainSignal() ConfigOption { return drainSignalOption{} }
 
-	return tlsOption{
-		value: &tls.Config{
-			ClientAuth:   tls.RequireAndVerifyClientCert,
-			Certificates: []tls.Certificate{ce},
-			ClientCAs:    pool,
-			MinVersion:   tls.VersionTLS12,
-			NextProtos:   []string{"h2", "http/1.1"},
-		},
-	}
+// WithOnDrain registers a hook called as soon as shutdown begins, before the drain delay, e.g. health.Registry.Drain.
+func WithOnDrain(v func()) ConfigOption { return onDrainOption{value: v} }
+
+// WithAfterShutdown registers a hook called once the server has shut down and in-flight requests have finished, e.g.
+// the Shutdown of a pgxkit.Client, so resources used by handlers are released only when no request needs them any
+// more. Hooks run in the order they were registered, within a timeout of ShutdownTimeout of their own.
+func WithAfterShutdown(v func(context.Context) error) ConfigOption {
+	return afterShutdownOption{value: v}
 }
 
+// WithDrainReporter registers a function called every second during shutdown with the number of in-flight requests.
+func WithDrainReporter(v func(int64)) ConfigOption { return drainReporterOption{value: v} }
+
+// WithHTTPRedirect runs a plaintext listener on addr, e.g. ":80", redirecting all requests to the HTTPS server. It
+// starts and shuts down together with the main server.
+func WithHTTPRedirect(addr string) ConfigOption { return httpRedirectOption{value: addr} }
+
+// WithACMEHTTPHandler lets the redirect listener answer ACME HTTP-01 challenges, e.g. with autocert.Manager's
+// HTTPHandler, which serves the challenges and passes everything else on to the redirect.
+func WithACMEHTTPHandler(v func(fallback http.Handler) http.Handler) ConfigOption {
+	return acmeOption{value: v}
+}
+
+// WithConnState registers a hook called on every connection state change, see http.Server.ConnState. Several hooks
+// may be registered, e.g. metrics.ConnState next to custom logging.
+func WithConnState(v func(net.Conn, http.ConnState)) ConfigOption { return connStateOption{value: v} }
+
+func WithConfig(v Config) ConfigOption                 { return configOption{value: v} }
+func WithConfigOptions(v ...ConfigOption) ConfigOption { return configOptions{value: v} }
+
 func (o hostOption) applyToConfig(cfg *Config)            { cfg.Host = o.value }
 func (o portOption) applyToConfig(cfg *Config)            { cfg.Port = o.value }
 func (o idleTimeoutOption) applyToConfig(cfg *Config)     { cfg.IdleTimeout = o.value }
 func (o readTimeoutOption) applyToConfig(cfg *Config)     { cfg.ReadTimeout = o.value }
 func (o writeTimeoutOption) applyToConfig(cfg *Config)    { cfg.WriteTimeout = o.value }
 func (o shutdownTimeoutOption) applyToConfig(cfg *Config) { cfg.ShutdownTimeout = o.value }
-func (o tlsOption) applyToConfig(cfg *Config)             { cfg.TLS, cfg.tlsErr = o.value, o.err }
+func (o drainDelayOption) applyToConfig(cfg *Config)      { cfg.DrainDelay = o.value }
+func (o drainReporterOption) applyToConfig(cfg *Config)   { cfg.drainReport = o.value }
+func (o tlsOption) applyToConfig(cfg *Config)             { cfg.loadTLS = o.value }
+func (o httpRedirectOption) applyToConfig(cfg *Config)    { cfg.redirect = o.value }
+func (o acmeOption) applyToConfig(cfg *Config)            { cfg.acme = o.value }
 func (o configOption) applyToConfig(cfg *Config)          { cfg.Override(o.value) }
 func (o configOptions) applyToConfig(cfg *Config) {
 	for _, opt := range o.value {
 		opt.applyToConfig(cfg)
 	}
 }
+
+func (o extraAddrsOption) applyToConfig(cfg *Config) {
+	cfg.ExtraAddrs = append(cfg.ExtraAddrs, o.value...)
+}
+
+func (o connStateOption) applyToConfig(cfg *Config) {
+	cfg.connState = append(cfg.connState, o.value)
+}
+
+func (o onShutdownOption) applyToConfig(cfg *Config) {
+	cfg.onShutdown = append(cfg.onShutdown, o.value)
+}
+
+func (o onDrainOption) applyToConfig(cfg *Config) {
+	cfg.onDrain = append(cfg.onDrain, o.value)
+}
+
+func (o afterShutdownOption) applyToConfig(cfg *Config) {
+	cfg.afterStop = append(cfg.afterStop, o.value)
+}
+
+func (gracefulRestartOption) applyToConfig(cfg *Config) { cfg.restart = true }
+func (reusePortOption) applyToConfig(cfg *Config)       { cfg.reusePort = true }
+func (drainSignalOption) applyToConfig(cfg *Config)     { cfg.drainSignal = true }
@@ -0,0 +1,32 @@
+package httpkit
+
+import "context"
+
+// ContextKey is a typed key for request-scoped values. Keys are compared by identity, so two keys never collide,
+// even with the same name and type. Create them once at package level with NewContextKey.
+type ContextKey[T any] struct {
+	name string
+}
+
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+func (k *ContextKey[T]) String() string { return "httpkit context key " + k.name }
+
+// ContextSet returns a copy of ctx carrying v under key.
+func ContextSet[T any](ctx context.Context, key *ContextKey[T], v T) context.Context {
+	return context.WithValue(ctx, key, v)
+}
+
+// ContextGet returns the value stored under key and whether there is one.
+func ContextGet[T any](ctx context.Context, key *ContextKey[T]) (T, bool) {
+	v, ok := ctx.Value(key).(T)
+	return v, ok
+}
+
+// ContextValue returns the value stored under key, or the zero value of T.
+func ContextValue[T any](ctx context.Context, key *ContextKey[T]) T {
+	v, _ := ContextGet(ctx, key)
+	return v
+}
@@ -0,0 +1,132 @@
+package httpkit
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+type debugConfig struct {
+	prefix string
+	trace  bool
+	auth   func(*http.Request) bool
+}
+
+// DebugMux serves pprof profiles, expvar variables and GC/heap statistics. It is meant for an admin server that is
+// not exposed publicly; WithDebugAuth or WithDebugBasicAuth should guard it anyway.
+func DebugMux(opts ...DebugOption) http.Handler {
+	cfg := debugConfig{prefix: "/debug"}
+	for _, opt := range opts {
+		opt.applyToDebug(&cfg)
+	}
+
+	mux := http.NewServeMux()
+	// pprof.Index only serves named profiles under /debug/pprof/, so they are routed explicitly for other prefixes.
+	mux.HandleFunc(cfg.prefix+"/pprof/", pprof.Index)
+	mux.HandleFunc(cfg.prefix+"/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+	})
+	mux.HandleFunc(cfg.prefix+"/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc(cfg.prefix+"/pprof/profile", pprof.Profile)
+	mux.HandleFunc(cfg.prefix+"/pprof/symbol", pprof.Symbol)
+	mux.Handle(cfg.prefix+"/vars", expvar.Handler())
+	mux.HandleFunc(cfg.prefix+"/runtime", runtimeStats)
+	mux.HandleFunc("POST "+cfg.prefix+"/runtime/gc", forceGC)
+
+	if cfg.trace {
+		mux.HandleFunc(cfg.prefix+"/pprof/trace", pprof.Trace)
+	}
+
+	if cfg.auth == nil {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.auth(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="debug"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+type runtimeStatsResponse struct {
+	Goroutines   int       `json:"goroutines"`
+	CPUs         int       `json:"cpus"`
+	GOMAXPROCS   int       `json:"gomaxprocs"`
+	HeapAlloc    uint64    `json:"heap_alloc_bytes"`
+	HeapInuse    uint64    `json:"heap_inuse_bytes"`
+	HeapObjects  uint64    `json:"heap_objects"`
+	Sys          uint64    `json:"sys_bytes"`
+	TotalAlloc   uint64    `json:"total_alloc_bytes"`
+	NumGC        uint32    `json:"num_gc"`
+	LastGC       time.Time `json:"last_gc"`
+	PauseTotalMS float64   `json:"pause_total_ms"`
+	NextGC       uint64    `json:"next_gc_bytes"`
+}
+
+func runtimeStats(w http.ResponseWriter, _ *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runtimeStatsResponse{
+		Goroutines:   runtime.NumGoroutine(),
+		CPUs:         runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		HeapAlloc:    ms.HeapAlloc,
+		HeapInuse:    ms.HeapInuse,
+		HeapObjects:  ms.HeapObjects,
+		Sys:          ms.Sys,
+		TotalAlloc:   ms.TotalAlloc,
+		NumGC:        ms.NumGC,
+		LastGC:       gc.LastGC,
+		PauseTotalMS: float64(gc.PauseTotal) / float64(time.Millisecond),
+		NextGC:       ms.NextGC,
+	})
+}
+
+func forceGC(w http.ResponseWriter, r *http.Request) {
+	debug.FreeOSMemory()
+	runtimeStats(w, r)
+}
+
+type DebugOption interface {
+	applyToDebug(*debugConfig)
+}
+
+type DebugOptionFunc func(*debugConfig)
+
+func (f DebugOptionFunc) applyToDebug(c *debugConfig) { f(c) }
+
+// WithDebugPrefix changes the path prefix the endpoints are mounted under, "/debug" by default.
+func WithDebugPrefix(prefix string) DebugOptionFunc {
+	return func(c *debugConfig) { c.prefix = prefix }
+}
+
+// WithDebugTrace enables the runtime/trace capture endpoint, which can be expensive on busy servers.
+func WithDebugTrace() DebugOptionFunc {
+	return func(c *debugConfig) { c.trace = true }
+}
+
+func WithDebugAuth(fn func(*http.Request) bool) DebugOptionFunc {
+	return func(c *debugConfig) { c.auth = fn }
+}
+
+func WithDebugBasicAuth(user, pass string) DebugOptionFunc {
+	return WithDebugAuth(func(r *http.Request) bool {
+		u, p, ok := r.BasicAuth()
+		return ok &&
+			subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(p), []byte(pass)) == 1
+	})
+}
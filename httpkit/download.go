@@ -0,0 +1,103 @@
+package httpkit
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachePolicy describes a Cache-Control header. Durations are rounded down to whole seconds; zero leaves the
+// directive out.
+type CachePolicy struct {
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	MustRevalidate       bool
+	Immutable            bool
+	MaxAge               time.Duration
+	SharedMaxAge         time.Duration
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+}
+
+var (
+	// CacheNoStore forbids any cache from keeping the response, e.g. for personal data.
+	CacheNoStore = CachePolicy{NoStore: true}
+	// CacheRevalidate lets caches keep the response but makes them check with the server before every use.
+	CacheRevalidate = CachePolicy{NoCache: true}
+	// CacheImmutable is for content that never changes under its URL, e.g. fingerprinted assets.
+	CacheImmutable = CachePolicy{Public: true, MaxAge: 365 * 24 * time.Hour, Immutable: true}
+)
+
+func (p CachePolicy) String() string {
+	var directives []string
+
+	flag := func(set bool, name string) {
+		if set {
+			directives = append(directives, name)
+		}
+	}
+
+	seconds := func(d time.Duration, name string) {
+		if d > 0 {
+			directives = append(directives, name+"="+strconv.FormatInt(int64(d/time.Second), 10))
+		}
+	}
+
+	flag(p.Public, "public")
+	flag(p.Private, "private")
+	flag(p.NoCache, "no-cache")
+	flag(p.NoStore, "no-store")
+	seconds(p.MaxAge, "max-age")
+	seconds(p.SharedMaxAge, "s-maxage")
+	flag(p.MustRevalidate, "must-revalidate")
+	flag(p.Immutable, "immutable")
+	seconds(p.StaleWhileRevalidate, "stale-while-revalidate")
+	seconds(p.StaleIfError, "stale-if-error")
+
+	return strings.Join(directives, ", ")
+}
+
+// SetCacheControl sets the Cache-Control header from p and a matching Expires header for HTTP/1.0 caches.
+func SetCacheControl(w http.ResponseWriter, p CachePolicy) {
+	h := w.Header()
+	h.Set("Cache-Control", p.String())
+
+	switch {
+	case p.NoStore || p.NoCache:
+		h.Set("Expires", "0")
+	case p.MaxAge > 0:
+		h.Set("Expires", time.Now().Add(p.MaxAge).UTC().Format(http.TimeFormat))
+	}
+}
+
+// SetAttachment makes browsers save the response as filename instead of displaying it. Non-ASCII names are encoded
+// as defined by RFC 6266.
+func SetAttachment(w http.ResponseWriter, filename string) {
+	setContentDisposition(w, "attachment", filename)
+}
+
+// SetInline makes browsers display the response, suggesting filename should the user save it.
+func SetInline(w http.ResponseWriter, filename string) {
+	setContentDisposition(w, "inline", filename)
+}
+
+func setContentDisposition(w http.ResponseWriter, disposition, filename string) {
+	if filename == "" {
+		w.Header().Set("Content-Disposition", disposition)
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+}
+
+// ServeDownload sends content as an attachment named filename. Range and If-Range requests are answered with
+// partial content so interrupted downloads can be resumed; set an ETag with SetETag beforehand to let clients resume
+// safely even when modtime is unknown. The content type is derived from the file extension or sniffed.
+func ServeDownload(w http.ResponseWriter, r *http.Request, filename string, modtime time.Time, content io.ReadSeeker) {
+	SetAttachment(w, filename)
+	http.ServeContent(w, r, filename, modtime, content)
+}
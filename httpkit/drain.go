@@ -0,0 +1,48 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDraining is the cause of contexts returned by DrainContext that are canceled because the server is draining.
+var ErrDraining = errors.New("httpkit: server is draining")
+
+var drainingKey = NewContextKey[<-chan struct{}]("draining")
+
+// Draining returns a channel that is closed when the server handling the request begins shutting down. Long-lived
+// handlers such as streams select on it to finish early and let clients reconnect elsewhere. Without
+// WithDrainSignal the channel is nil and never closes.
+func Draining(ctx context.Context) <-chan struct{} {
+	return ContextValue(ctx, drainingKey)
+}
+
+func IsDraining(ctx context.Context) bool {
+	select {
+	case <-Draining(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// DrainContext returns a copy of ctx that is also canceled, with cause ErrDraining, when the server begins
+// draining.
+func DrainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	draining := Draining(ctx)
+	if draining == nil {
+		return ctx, func() { cancel(context.Canceled) }
+	}
+
+	go func() {
+		select {
+		case <-draining:
+			cancel(ErrDraining)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() { cancel(context.Canceled) }
+}
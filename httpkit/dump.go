@@ -0,0 +1,272 @@
+package httpkit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultDumpMaxBytes = 64 << 10
+	_redacted            = "[REDACTED]"
+	_allRoutes           = "*"
+)
+
+var _defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Dump is a captured request and response. Bodies are cut off after the configured size.
+type Dump struct {
+	Time              time.Time     `json:"time"`
+	Method            string        `json:"method"`
+	URL               string        `json:"url"`
+	Route             string        `json:"route,omitempty"`
+	RequestHeader     http.Header   `json:"request_header"`
+	RequestBody       []byte        `json:"request_body,omitempty"`
+	RequestTruncated  bool          `json:"request_truncated,omitempty"`
+	Status            int           `json:"status"`
+	ResponseHeader    http.Header   `json:"response_header"`
+	ResponseBody      []byte        `json:"response_body,omitempty"`
+	ResponseTruncated bool          `json:"response_truncated,omitempty"`
+	Duration          time.Duration `json:"duration"`
+}
+
+// DumpSink receives captured dumps, e.g. to log them or keep them for inspection.
+type DumpSink func(ctx context.Context, d *Dump)
+
+// DumpLogger logs dumps at debug level with bodies as strings.
+func DumpLogger(log *slog.Logger) DumpSink {
+	return func(ctx context.Context, d *Dump) {
+		log.DebugContext(ctx, "http dump",
+			slog.String("method", d.Method),
+			slog.String("url", d.URL),
+			slog.String("route", d.Route),
+			slog.Int("status", d.Status),
+			slog.Duration("duration", d.Duration),
+			slog.Any("request_header", d.RequestHeader),
+			slog.String("request_body", string(d.RequestBody)),
+			slog.Any("response_header", d.ResponseHeader),
+			slog.String("response_body", string(d.ResponseBody)),
+		)
+	}
+}
+
+// Dumper captures full requests and responses of selected routes for debugging client integrations. Capturing is
+// off until enabled for a route pattern, or for all routes with "*", so the middleware can stay in production
+// builds. Sensitive headers are redacted.
+type Dumper struct {
+	sink     DumpSink
+	maxBytes int
+	redact   []string
+
+	mu     sync.RWMutex
+	routes map[string]bool
+}
+
+func NewDumper(sink DumpSink, opts ...DumperOption) *Dumper {
+	d := Dumper{
+		sink:     sink,
+		maxBytes: _defaultDumpMaxBytes,
+		redact:   _defaultRedactedHeaders,
+		routes:   make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt.applyToDumper(&d)
+	}
+
+	return &d
+}
+
+// Enable starts capturing requests matching the route pattern, or all requests for "*".
+func (d *Dumper) Enable(route string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.routes[route] = true
+}
+
+func (d *Dumper) Disable(route string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.routes, route)
+}
+
+// Routes returns the route patterns currently captured.
+func (d *Dumper) Routes() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	routes := make([]string, 0, len(d.routes))
+	for route := range d.routes {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+
+	return routes
+}
+
+func (d *Dumper) active() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.routes) > 0
+}
+
+func (d *Dumper) captures(route string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.routes[_allRoutes] || d.routes[route]
+}
+
+// Middleware captures requests while any route is enabled. The route is only known once the handler returned, so
+// bodies are recorded for every request and dropped unless the matched route is enabled.
+func (d *Dumper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.active() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		r = CaptureRoute(r)
+
+		reqBody := &capture{limit: d.maxBytes}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &captureReader{ReadCloser: r.Body, capture: reqBody}
+		}
+
+		rw := &dumpWriter{ResponseWriter: WrapResponseWriter(w), capture: capture{limit: d.maxBytes}}
+
+		next.ServeHTTP(rw, r)
+
+		route := RoutePattern(r)
+		if !d.captures(route) {
+			return
+		}
+
+		d.sink(r.Context(), &Dump{
+			Time:              start,
+			Method:            r.Method,
+			URL:               r.URL.String(),
+			Route:             route,
+			RequestHeader:     d.redactHeader(r.Header),
+			RequestBody:       reqBody.buf,
+			RequestTruncated:  reqBody.truncated,
+			Status:            rw.Status(),
+			ResponseHeader:    d.redactHeader(rw.Header()),
+			ResponseBody:      rw.buf,
+			ResponseTruncated: rw.truncated,
+			Duration:          time.Since(start),
+		})
+	})
+}
+
+// Handler is an admin endpoint listing the captured routes on GET, enabling capturing for the route given in the
+// "route" query parameter on POST and disabling it on DELETE.
+func (d *Dumper) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Query().Get("route")
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodDelete:
+			if route == "" {
+				http.Error(w, "route query parameter is required", http.StatusBadRequest)
+				return
+			}
+			if r.Method == http.MethodPost {
+				d.Enable(route)
+			} else {
+				d.Disable(route)
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string][]string{"routes": d.Routes()})
+	})
+}
+
+func (d *Dumper) redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range d.redact {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, _redacted)
+		}
+	}
+	return h
+}
+
+// capture keeps the first limit bytes written to it.
+type capture struct {
+	buf       []byte
+	limit     int
+	truncated bool
+}
+
+func (c *capture) record(p []byte) {
+	if n := c.limit - len(c.buf); n < len(p) {
+		p = p[:max(n, 0)]
+		c.truncated = true
+	}
+	c.buf = append(c.buf, p...)
+}
+
+type captureReader struct {
+	io.ReadCloser
+	*capture
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.record(p[:n])
+	return n, err
+}
+
+type dumpWriter struct {
+	ResponseWriter
+	capture
+}
+
+func (w *dumpWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.record(p[:n])
+	return n, err
+}
+
+// Unwrap returns the wrapped writer so http.ResponseController reaches Flush and Hijack.
+func (w *dumpWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+type DumperOption interface {
+	applyToDumper(*Dumper)
+}
+
+type DumperOptionFunc func(*Dumper)
+
+func (f DumperOptionFunc) applyToDumper(d *Dumper) { f(d) }
+
+// WithDumpMaxBytes sets how much of each body is captured. The default is 64 KiB.
+func WithDumpMaxBytes(n int) DumperOptionFunc {
+	return func(d *Dumper) { d.maxBytes = n }
+}
+
+// WithDumpRedactHeaders adds headers whose values are replaced in dumps, on top of Authorization, Cookie and the
+// like.
+func WithDumpRedactHeaders(headers ...string) DumperOptionFunc {
+	return func(d *Dumper) { d.redact = append(slices.Clip(d.redact), headers...) }
+}
+
+// WithDumpRoutes enables capturing for the given route patterns from the start.
+func WithDumpRoutes(routes ...string) DumperOptionFunc {
+	return func(d *Dumper) {
+		for _, route := range routes {
+			d.routes[route] = true
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+// StatusClientClosedRequest is the non-standard status, introduced by nginx, logged for requests whose client went
+// away before the response was written.
+const StatusClientClosedRequest = 499
+
+// Error is an error carrying the status code to respond with and a message that is safe to show to clients.
+type Error struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func NewError(status int, err error) *Error {
+	return &Error{Status: status, Message: http.StatusText(status), Err: err}
+}
+
+func Errorf(status int, format string, args ...any) *Error {
+	msg := fmt.Sprintf(format, args...)
+	return &Error{Status: status, Message: msg, Err: errors.New(msg)}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// HandlerFunc is a handler that returns its error instead of writing it, leaving the status code and response body
+// to an ErrorHandler.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP handles the returned error with DefaultErrorHandler. Use ErrorHandler.Handle for a custom one.
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	DefaultErrorHandler.Handle(f).ServeHTTP(w, r)
+}
+
+var DefaultErrorHandler = NewErrorHandler()
+
+// ProblemError is implemented by errors that know how they are rendered as problem details.
+type ProblemError interface {
+	error
+	Problem() *problem.Details
+}
+
+type errorMapping struct {
+	target  error
+	status  int
+	problem *problem.Details
+}
+
+type ErrorMapper func(err error) (status int, ok bool)
+
+type ErrorHandler struct {
+	mappings []errorMapping
+	mappers  []ErrorMapper
+	log      *slog.Logger
+	render   func(w http.ResponseWriter, r *http.Request, status int, msg string, err error)
+}
+
+// NewErrorHandler maps expired deadlines to 504 Gateway Timeout and canceled requests to 499. Database errors are
+// mapped by pgstore.ErrorMappings.
+func NewErrorHandler(opts ...ErrorHandlerOption) *ErrorHandler {
+	h := ErrorHandler{
+		mappings: []errorMapping{
+			{target: context.DeadlineExceeded, status: http.StatusGatewayTimeout},
+			{
+				target:  context.Canceled,
+				status:  StatusClientClosedRequest,
+				problem: problem.New(StatusClientClosedRequest).WithType(problem.BlankType, "Client Closed Request"),
+			},
+		},
+	}
+	h.render = h.renderProblem
+
+	for _, opt := range opts {
+		opt.applyToErrorHandler(&h)
+	}
+
+	return &h
+}
+
+func (h *ErrorHandler) Handle(fn HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := WrapResponseWriter(w)
+		if err := fn(rw, r); err != nil {
+			h.HandleError(rw, r, err)
+		}
+	})
+}
+
+// HandleError writes the error response for err. If w is a ResponseWriter that already sent its headers, the
+// response can no longer be changed, so the error is only logged.
+func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	if rw, ok := w.(ResponseWriter); ok && rw.Written() {
+		if h.log != nil {
+			h.log.ErrorContext(r.Context(), "handling request after response was written",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.Status()),
+				slog.Int64("bytes", rw.BytesWritten()),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+		return
+	}
+
+	status, msg := h.Map(err)
+
+	if status >= http.StatusInternalServerError && h.log != nil {
+		h.log.ErrorContext(r.Context(), "handling request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Group("error", slog.String("msg", err.Error())),
+		)
+	}
+
+	h.render(w, r, status, msg, err)
+}
+
+// Map resolves the status code and client message for err. Errors that are not mapped become 500 Internal Server
+// Error with a generic message so internal details do not leak.
+func (h *ErrorHandler) Map(err error) (int, string) {
+	var herr *Error
+	if errors.As(err, &herr) {
+		return herr.Status, herr.Message
+	}
+
+	var pd *problem.Details
+	if errors.As(err, &pd) && pd.Status != 0 {
+		return pd.Status, pd.Detail
+	}
+
+	var perr ProblemError
+	if errors.As(err, &perr) {
+		pd := perr.Problem()
+		return pd.Status, pd.Detail
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge)
+	}
+
+	for _, fn := range h.mappers {
+		if status, ok := fn(err); ok {
+			return status, http.StatusText(status)
+		}
+	}
+
+	for _, m := range h.mappings {
+		if errors.Is(err, m.target) {
+			return m.status, http.StatusText(m.status)
+		}
+	}
+
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
+
+// renderProblem writes err as RFC 9457 problem details. Errors that are, or wrap, a *problem.Details are written
+// as is; everything else becomes an "about:blank" problem for status.
+func (h *ErrorHandler) renderProblem(w http.ResponseWriter, r *http.Request, status int, msg string, err error) {
+	pd := h.Problem(err, status, msg)
+	if pd.Instance == "" {
+		pd.Instance = r.URL.Path
+	}
+	_ = problem.Write(w, pd)
+}
+
+// Problem converts err into the problem details document it is rendered as.
+func (h *ErrorHandler) Problem(err error, status int, msg string) *problem.Details {
+	var pd *problem.Details
+	if errors.As(err, &pd) {
+		return pd.Clone()
+	}
+
+	var perr ProblemError
+	if errors.As(err, &perr) {
+		return perr.Problem()
+	}
+
+	for _, m := range h.mappings {
+		if m.problem != nil && errors.Is(err, m.target) {
+			return m.problem.Clone()
+		}
+	}
+
+	pd = problem.New(status)
+	if msg != pd.Title {
+		pd.Detail = msg
+	}
+
+	return pd
+}
+
+type ErrorHandlerOption interface {
+	applyToErrorHandler(*ErrorHandler)
+}
+
+type ErrorHandlerOptionFunc func(*ErrorHandler)
+
+func (f ErrorHandlerOptionFunc) applyToErrorHandler(h *ErrorHandler) { f(h) }
+
+// WithErrorStatus maps errors matching target with errors.Is to status. Later mappings take precedence.
+func WithErrorStatus(target error, status int) ErrorHandlerOptionFunc {
+	return func(h *ErrorHandler) {
+		h.mappings = append([]errorMapping{{target: target, status: status}}, h.mappings...)
+	}
+}
+
+// WithErrorProblem maps errors matching target with errors.Is to a copy of p, so domain errors are rendered as
+// consistent problem documents.
+func WithErrorProblem(target error, p *problem.Details) ErrorHandlerOptionFunc {
+	return func(h *ErrorHandler) {
+		h.mappings = append([]errorMapping{{target: target, status: p.Status, problem: p}}, h.mappings...)
+	}
+}
+
+// WithErrorMapper adds a function mapping errors to status codes, consulted before the WithErrorStatus mappings.
+func WithErrorMapper(fn ErrorMapper) ErrorHandlerOptionFunc {
+	return func(h *ErrorHandler) { h.mappers = append(h.mappers, fn) }
+}
+
+// WithErrorLogger logs errors resulting in a 5xx response.
+func WithErrorLogger(log *slog.Logger) ErrorHandlerOptionFunc {
+	return func(h *ErrorHandler) { h.log = log }
+}
+
+// WithErrorRenderer replaces how error responses are written.
+func WithErrorRenderer(fn func(w http.ResponseWriter, r *http.Request, status int, msg string, err error)) ErrorHandlerOptionFunc {
+	return func(h *ErrorHandler) { h.render = fn }
+}
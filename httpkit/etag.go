@@ -0,0 +1,163 @@
+package httpkit
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const _defaultETagMaxSize = 1 << 20
+
+type etagConfig struct {
+	weak    bool
+	maxSize int
+}
+
+// ETag buffers successful GET and HEAD responses, tags them with an ETag derived from the body unless the handler
+// already set one, and answers If-None-Match and If-Modified-Since with 304 Not Modified.
+func ETag(opts ...ETagOption) Middleware {
+	cfg := etagConfig{maxSize: _defaultETagMaxSize}
+	for _, opt := range opts {
+		opt.applyToETag(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := newResponseBuffer(w, cfg.maxSize)
+			next.ServeHTTP(buf, r)
+
+			if buf.passthrough {
+				return
+			}
+
+			if buf.statusCode() == http.StatusOK && buf.header.Get("ETag") == "" {
+				buf.header.Set("ETag", computeETag(buf.body.Bytes(), cfg.weak))
+			}
+
+			if buf.statusCode() == http.StatusOK && NotModified(r, buf.header) {
+				writeNotModified(w, buf.header)
+				return
+			}
+
+			_ = buf.writeTo(w)
+		})
+	}
+}
+
+func computeETag(b []byte, weak bool) string {
+	sum := sha256.Sum256(b)
+	tag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+func SetETag(w http.ResponseWriter, tag string, weak bool) {
+	if !strings.HasPrefix(tag, `"`) {
+		tag = `"` + tag + `"`
+	}
+	if weak {
+		tag = "W/" + tag
+	}
+	w.Header().Set("ETag", tag)
+}
+
+func SetLastModified(w http.ResponseWriter, t time.Time) {
+	if !t.IsZero() {
+		w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// CheckNotModified is for handlers that set their own validators with SetETag and SetLastModified: it writes a 304
+// and returns true if the client's cached copy is still fresh, in which case the handler must not write a body.
+func CheckNotModified(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if !NotModified(r, w.Header()) {
+		return false
+	}
+
+	writeNotModified(w, w.Header())
+	return true
+}
+
+// NotModified reports whether the request's conditional headers match the validators in h. If-None-Match takes
+// precedence over If-Modified-Since as required by RFC 9110.
+func NotModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("ETag")
+		return etag != "" && etagMatch(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	lm := h.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// etagMatch performs the weak comparison used for If-None-Match.
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeNotModified(w http.ResponseWriter, h http.Header) {
+	dst := w.Header()
+	for _, k := range []string{"ETag", "Last-Modified", "Cache-Control", "Content-Location", "Date", "Expires", "Vary"} {
+		if v, ok := h[k]; ok {
+			dst[k] = v
+		}
+	}
+	for _, k := range []string{"Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding"} {
+		dst.Del(k)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+type ETagOption interface {
+	applyToETag(*etagConfig)
+}
+
+type ETagOptionFunc func(*etagConfig)
+
+func (f ETagOptionFunc) applyToETag(c *etagConfig) { f(c) }
+
+func WithWeakETag() ETagOptionFunc {
+	return func(c *etagConfig) { c.weak = true }
+}
+
+// WithETagMaxSize sets the largest body that is buffered for hashing. Larger responses are streamed untagged.
+func WithETagMaxSize(n int) ETagOptionFunc {
+	return func(c *etagConfig) { c.maxSize = n }
+}
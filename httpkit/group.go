@@ -0,0 +1,75 @@
+package httpkit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Group registers routes on a Router under a common prefix, wrapping their handlers with the group's middlewares.
+type Group struct {
+	rt     *Router
+	prefix string
+	mws    []Middleware
+}
+
+// Group creates a group under prefix whose routes are wrapped by the router's middlewares followed by mws.
+func (rt *Router) Group(prefix string, mws ...Middleware) *Group {
+	return newGroup(rt, "", rt.mws, prefix, mws)
+}
+
+// Group creates a nested group whose prefix and middlewares extend those of g.
+func (g *Group) Group(prefix string, mws ...Middleware) *Group {
+	return newGroup(g.rt, g.prefix, g.mws, prefix, mws)
+}
+
+func newGroup(rt *Router, parentPrefix string, parentMws []Middleware, prefix string, mws []Middleware) *Group {
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Sprintf("httpkit: group prefix %q must start with /", prefix))
+	}
+
+	all := make([]Middleware, 0, len(parentMws)+len(mws))
+	all = append(append(all, parentMws...), mws...)
+
+	return &Group{rt: rt, prefix: parentPrefix + strings.TrimSuffix(prefix, "/"), mws: all}
+}
+
+// Use appends middlewares wrapping the handlers of routes registered on g afterwards.
+func (g *Group) Use(mws ...Middleware) {
+	g.mws = append(g.mws, mws...)
+}
+
+func (g *Group) Handle(method, pattern string, h http.Handler, opts ...RouteOption) {
+	if h == nil {
+		panic(fmt.Sprintf("httpkit: nil handler for %s %q", method, g.prefix+pattern))
+	}
+	g.rt.register(method, g.prefix+pattern, Chain(g.mws...)(h), newRouteConfig(opts))
+}
+
+func (g *Group) HandleFunc(method, pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(method, pattern, fn, opts...)
+}
+
+func (g *Group) Get(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodGet, pattern, fn, opts...)
+}
+
+func (g *Group) Head(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodHead, pattern, fn, opts...)
+}
+
+func (g *Group) Post(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodPost, pattern, fn, opts...)
+}
+
+func (g *Group) Put(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodPut, pattern, fn, opts...)
+}
+
+func (g *Group) Patch(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodPatch, pattern, fn, opts...)
+}
+
+func (g *Group) Delete(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	g.Handle(http.MethodDelete, pattern, fn, opts...)
+}
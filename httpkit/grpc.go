@@ -0,0 +1,98 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// GRPCServer is implemented by *grpc.Server.
+type GRPCServer interface {
+	http.Handler
+	Stop()
+}
+
+type grpcOption struct{ value GRPCServer }
+
+// WithGRPC serves gRPC next to the HTTP handler on the same port, dispatching HTTP/2 requests with a gRPC content
+// type to srv. Without TLS, HTTP/2 is accepted in cleartext (h2c). The server shares the HTTP server's lifecycle:
+// shutdown waits for in-flight calls within the shutdown timeout and stops whatever is still running after it.
+func WithGRPC(srv GRPCServer) ConfigOption { return grpcOption{value: srv} }
+
+func (o grpcOption) applyToConfig(cfg *Config) { cfg.grpc = o.value }
+
+// GRPCHandler dispatches gRPC requests to grpc and everything else to h.
+func GRPCHandler(grpc, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsGRPC(r) {
+			grpc.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func IsGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// grpcServerHandler wraps h for WithGRPC, accepting cleartext HTTP/2 unless the server uses TLS. Calls are tracked
+// in calls, as cleartext HTTP/2 connections are hijacked from the server and not waited for by its Shutdown.
+func grpcServerHandler(srv GRPCServer, h http.Handler, useTLS bool, calls *grpcCalls) http.Handler {
+	h = GRPCHandler(calls.track(srv), h)
+	if useTLS {
+		return h
+	}
+	return h2c.NewHandler(h, &http2.Server{})
+}
+
+// grpcCalls counts the gRPC calls in flight, so shutdown can wait for them to finish.
+type grpcCalls struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func (c *grpcCalls) track(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.n++
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.n--; c.n == 0 && c.idle != nil {
+				close(c.idle)
+				c.idle = nil
+			}
+		}()
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// wait returns once no call is in flight, or with the error of ctx when it is done first.
+func (c *grpcCalls) wait(ctx context.Context) error {
+	c.mu.Lock()
+	if c.n == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	if c.idle == nil {
+		c.idle = make(chan struct{})
+	}
+	idle := c.idle
+	c.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
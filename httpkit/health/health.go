@@ -0,0 +1,288 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+const _defaultTimeout = 5 * time.Second
+
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded"
+	StatusFail     Status = "fail"
+)
+
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// DB checks database connectivity by pinging the pgxkit pool or client.
+func DB(db interface{ Ping(context.Context) error }) Checker {
+	return CheckerFunc(db.Ping)
+}
+
+// DBHealth checks the database with the client's HealthCheck, which can also run a query, see
+// pgxkit.WithHealthQuery.
+func DBHealth(db pgxkit.HealthChecker) Checker {
+	return CheckerFunc(db.HealthCheck)
+}
+
+// Result is the outcome of a check. Error is logged by the registry but not exposed by its handlers, as it may reveal
+// internals such as host names.
+type Result struct {
+	Status     Status    `json:"status"`
+	Error      string    `json:"-"`
+	Critical   bool      `json:"critical"`
+	Cached     bool      `json:"cached,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status   Status            `json:"status"`
+	Draining bool              `json:"draining,omitempty"`
+	Checks   map[string]Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	cacheTTL time.Duration
+	critical bool
+	liveness bool
+
+	mu     sync.Mutex
+	result Result
+}
+
+type Registry struct {
+	mu       sync.RWMutex
+	checks   []*check
+	draining atomic.Bool
+	log      *slog.Logger
+}
+
+func NewRegistry(opts ...RegistryOption) *Registry {
+	reg := Registry{log: slog.Default()}
+	for _, opt := range opts {
+		opt.applyToRegistry(&reg)
+	}
+	return &reg
+}
+
+// Register adds a readiness check. Checks are critical unless registered with NonCritical, in which case a failure
+// only degrades the reported status. Registering a name twice replaces the previous check.
+func (reg *Registry) Register(name string, c Checker, opts ...CheckOption) {
+	chk := &check{name: name, checker: c, timeout: _defaultTimeout, critical: true}
+	for _, opt := range opts {
+		opt.applyToCheck(chk)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for i, existing := range reg.checks {
+		if existing.name == name {
+			reg.checks[i] = chk
+			return
+		}
+	}
+
+	reg.checks = append(reg.checks, chk)
+}
+
+func (reg *Registry) Unregister(name string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for i, c := range reg.checks {
+		if c.name == name {
+			reg.checks = append(reg.checks[:i], reg.checks[i+1:]...)
+			return
+		}
+	}
+}
+
+// Liveness runs the checks registered with Liveness. With none registered it reports ok, meaning the process is up.
+func (reg *Registry) Liveness(ctx context.Context) Report {
+	return reg.run(ctx, func(c *check) bool { return c.liveness })
+}
+
+// Readiness runs every check that is not a liveness check. Once Drain is called it fails without running them.
+func (reg *Registry) Readiness(ctx context.Context) Report {
+	if reg.draining.Load() {
+		return Report{Status: StatusFail, Draining: true}
+	}
+	return reg.run(ctx, func(c *check) bool { return !c.liveness })
+}
+
+// Drain makes readiness fail so load balancers stop routing new requests here. Pass it to httpkit.WithOnDrain.
+func (reg *Registry) Drain() {
+	reg.draining.Store(true)
+}
+
+func (reg *Registry) LivenessHandler() http.Handler { return reportHandler(reg.Liveness) }
+
+func (reg *Registry) ReadinessHandler() http.Handler { return reportHandler(reg.Readiness) }
+
+// Mount registers the handlers on mux under /livez and /readyz.
+func (reg *Registry) Mount(mux *http.ServeMux) {
+	mux.Handle("GET /livez", reg.LivenessHandler())
+	mux.Handle("GET /readyz", reg.ReadinessHandler())
+}
+
+func (reg *Registry) run(ctx context.Context, include func(*check) bool) Report {
+	reg.mu.RLock()
+	var checks []*check
+	for _, c := range reg.checks {
+		if include(c) {
+			checks = append(checks, c)
+		}
+	}
+	reg.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, reg.log)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.name] = res
+
+		switch {
+		case res.Status == StatusOK:
+		case res.Critical:
+			report.Status = StatusFail
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (c *check) run(ctx context.Context, log *slog.Logger) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cacheTTL > 0 && !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.cacheTTL {
+		res := c.result
+		res.Cached = true
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.checker.Check(ctx)
+
+	res := Result{
+		Status:     StatusOK,
+		Critical:   c.critical,
+		DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+		CheckedAt:  start,
+	}
+
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+
+		if log != nil {
+			log.WarnContext(ctx, "health check failed",
+				slog.String("check", c.name),
+				slog.Bool("critical", c.critical),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+	}
+
+	c.result = res
+
+	return res
+}
+
+func reportHandler(fn func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := fn(r.Context())
+
+		status := http.StatusOK
+		if report.Status == StatusFail {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+
+		if r.URL.Query().Has("verbose") || report.Status != StatusOK {
+			_ = json.NewEncoder(w).Encode(report)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(Report{Status: report.Status})
+	})
+}
+
+type RegistryOption interface {
+	applyToRegistry(*Registry)
+}
+
+type RegistryOptionFunc func(*Registry)
+
+func (f RegistryOptionFunc) applyToRegistry(reg *Registry) { f(reg) }
+
+// WithLogger sets the logger failed checks are reported to, slog.Default unless set. Nil disables logging.
+func WithLogger(log *slog.Logger) RegistryOptionFunc {
+	return func(reg *Registry) { reg.log = log }
+}
+
+type CheckOption interface {
+	applyToCheck(*check)
+}
+
+type CheckOptionFunc func(*check)
+
+func (f CheckOptionFunc) applyToCheck(c *check) { f(c) }
+
+func WithTimeout(d time.Duration) CheckOptionFunc {
+	return func(c *check) { c.timeout = d }
+}
+
+// WithCacheTTL reuses the last result for d, protecting expensive dependencies from aggressive probing.
+func WithCacheTTL(d time.Duration) CheckOptionFunc {
+	return func(c *check) { c.cacheTTL = d }
+}
+
+func NonCritical() CheckOptionFunc {
+	return func(c *check) { c.critical = false }
+}
+
+// Liveness reports the check on /livez instead of /readyz. Only checks whose failure warrants a restart belong there.
+func Liveness() CheckOptionFunc {
+	return func(c *check) { c.liveness = true }
+}
@@ -0,0 +1,122 @@
+package httpkit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type hostWildcard struct {
+	suffix  string
+	handler http.Handler
+}
+
+// HostMux dispatches requests by host name. Patterns are either exact host names such as "api.example.com" or
+// wildcards such as "*.example.com" matching exactly one additional label. Exact names win over wildcards and longer
+// wildcards win over shorter ones. The label matched by a wildcard is available through Subdomain.
+type HostMux struct {
+	mu        sync.RWMutex
+	exact     map[string]http.Handler
+	wildcards []hostWildcard
+	notFound  http.Handler
+}
+
+func NewHostMux(opts ...HostMuxOption) *HostMux {
+	m := HostMux{exact: make(map[string]http.Handler), notFound: http.NotFoundHandler()}
+	for _, opt := range opts {
+		opt.applyToHostMux(&m)
+	}
+	return &m
+}
+
+// Handle registers h for pattern. It panics if the pattern is already registered.
+func (m *HostMux) Handle(pattern string, h http.Handler) {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		for _, wc := range m.wildcards {
+			if wc.suffix == suffix {
+				panic(fmt.Sprintf("httpkit: host pattern %q already registered", pattern))
+			}
+		}
+
+		m.wildcards = append(m.wildcards, hostWildcard{suffix: suffix, handler: h})
+		sort.SliceStable(m.wildcards, func(i, j int) bool {
+			return len(m.wildcards[i].suffix) > len(m.wildcards[j].suffix)
+		})
+
+		return
+	}
+
+	if _, ok := m.exact[pattern]; ok {
+		panic(fmt.Sprintf("httpkit: host pattern %q already registered", pattern))
+	}
+
+	m.exact[pattern] = h
+}
+
+func (m *HostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := requestHost(r)
+
+	m.mu.RLock()
+	h, ok := m.exact[host]
+	if !ok {
+		for _, wc := range m.wildcards {
+			label, found := strings.CutSuffix(host, "."+wc.suffix)
+			if found && label != "" && !strings.Contains(label, ".") {
+				h, ok = wc.handler, true
+				r = r.WithContext(ContextSet(r.Context(), subdomainKey, label))
+				break
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		m.notFound.ServeHTTP(w, r)
+		return
+	}
+
+	h.ServeHTTP(w, r)
+}
+
+// requestHost returns the lower-cased Host header without port, falling back to the TLS server name.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if host == "" && r.TLS != nil {
+		host = r.TLS.ServerName
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+var subdomainKey = NewContextKey[string]("subdomain")
+
+// Subdomain returns the label matched by a HostMux wildcard pattern, e.g. "acme" for "acme.example.com" routed by
+// "*.example.com".
+func Subdomain(r *http.Request) string {
+	return ContextValue(r.Context(), subdomainKey)
+}
+
+type HostMuxOption interface {
+	applyToHostMux(*HostMux)
+}
+
+type HostMuxOptionFunc func(*HostMux)
+
+func (f HostMuxOptionFunc) applyToHostMux(m *HostMux) { f(m) }
+
+// WithHostFallback sets the handler for hosts matching no pattern, 404 Not Found by default.
+func WithHostFallback(h http.Handler) HostMuxOptionFunc {
+	return func(m *HostMux) { m.notFound = h }
+}
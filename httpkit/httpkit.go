@@ -3,9 +3,12 @@ package httpkit
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -21,44 +24,190 @@ func Serve(ctx context.Context, h http.Handler, opts ...ConfigOption) error {
 		return err
 	}
 
+	if cfg.grpc != nil {
+		cfg.grpcCalls = &grpcCalls{}
+		h = grpcServerHandler(cfg.grpc, h, cfg.TLS != nil, cfg.grpcCalls)
+	}
+
+	warmup := newWarmupGate(cfg.warmup)
+	if len(cfg.warmup) > 0 {
+		h = warmup.wrap(h)
+	}
+
+	var inFlight atomic.Int64
+
 	srv := &http.Server{
-		Addr:         cfg.Addr(),
-		Handler:      h,
+		Addr: cfg.Addr(),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+			h.ServeHTTP(w, r)
+		}),
 		IdleTimeout:  cfg.IdleTimeout,
 		ReadTimeout:  cfg.ReadTimeout,
 		WriteTimeout: cfg.WriteTimeout,
 		TLSConfig:    cfg.TLS,
 	}
 
-	eg, egCtx, stop := withErrGroupNotifyContext(ctx)
-	defer stop()
+	if len(cfg.connState) > 0 {
+		srv.ConnState = func(c net.Conn, state http.ConnState) {
+			for _, fn := range cfg.connState {
+				fn(c, state)
+			}
+		}
+	}
 
-	eg.Go(func() error {
-		if err := open(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			return err
+	if cfg.drainSignal {
+		draining := make(chan struct{})
+		srv.BaseContext = func(net.Listener) context.Context {
+			return ContextSet(context.Background(), drainingKey, (<-chan struct{})(draining))
 		}
-		return nil
-	})
+		cfg.onDrain = append([]func(){func() { close(draining) }}, cfg.onDrain...)
+	}
+
+	// Hooks run when shutdown begins, so connections hijacked from the server, such as websockets, can be closed.
+	for _, fn := range cfg.onShutdown {
+		srv.RegisterOnShutdown(fn)
+	}
+
+	lns, err := listen(&cfg)
+	if err != nil {
+		return err
+	}
+
+	// The redirect listener is opened along with the others, so it is shared with a restarted process as well.
+	srvLns := lns
+	if cfg.redirect != "" {
+		srvLns = lns[:len(lns)-1]
+	}
+
+	restartCtx, restart := context.WithCancel(ctx)
+	defer restart()
+
+	eg, egCtx, stop := withErrGroupNotifyContext(restartCtx)
+	defer stop()
+
+	// Serving may set up a TLS config for HTTP/2, so whether to serve TLS must be decided before the first listener
+	// is opened. All listeners share the server, so Shutdown closes them together.
+	useTLS := srv.TLSConfig != nil
+	for _, ln := range srvLns {
+		eg.Go(func() error {
+			if err := open(srv, ln, useTLS); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if len(cfg.warmup) > 0 {
+		eg.Go(func() error { return warmup.run(egCtx) })
+	}
+
+	if cfg.restart {
+		eg.Go(func() error { return watchRestart(egCtx, lns, &cfg, restart) })
+	}
+
+	if cfg.redirect != "" {
+		rsrv := redirectServer(cfg.redirect, cfg.Port, cfg.acme)
+
+		eg.Go(func() error {
+			if err := rsrv.Serve(lns[len(lns)-1]); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+
+		eg.Go(func() error {
+			<-egCtx.Done()
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ShutdownTimeout)
+			defer cancel()
+
+			return rsrv.Shutdown(ctx)
+		})
+	}
 
 	eg.Go(func() error {
 		<-egCtx.Done()
-		shutdownCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
-		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+		return shutdown(context.WithoutCancel(ctx), srv, &cfg, &inFlight)
 	})
 
 	return eg.Wait()
 }
 
+// shutdown stops keep-alives so clients move to other instances, runs the drain hooks and waits for the drain delay
+// while still serving, then shuts the server down within the shutdown timeout and runs the after shutdown hooks.
+func shutdown(ctx context.Context, srv *http.Server, cfg *Config, inFlight *atomic.Int64) error {
+	srv.SetKeepAlivesEnabled(false)
+
+	for _, fn := range cfg.onDrain {
+		fn()
+	}
+
+	if cfg.DrainDelay > 0 {
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+	defer cancel()
+
+	if cfg.drainReport != nil {
+		go func() {
+			t := time.NewTicker(time.Second)
+			defer t.Stop()
+
+			for {
+				cfg.drainReport(inFlight.Load())
+
+				select {
+				case <-shutdownCtx.Done():
+					return
+				case <-t.C:
+				}
+			}
+		}()
+	}
+
+	err := srv.Shutdown(shutdownCtx)
+
+	// Shutdown does not wait for gRPC calls on hijacked cleartext HTTP/2 connections, so they are waited for here
+	// within what is left of the timeout. Streams outliving it are stopped.
+	if cfg.grpc != nil {
+		_ = cfg.grpcCalls.wait(shutdownCtx)
+		cfg.grpc.Stop()
+	}
+
+	if len(cfg.afterStop) == 0 {
+		return err
+	}
+
+	// The hooks get a timeout of their own, as the requests may have used up most of the shutdown timeout.
+	hookCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+	defer cancel()
+
+	errs := []error{err}
+	for _, fn := range cfg.afterStop {
+		errs = append(errs, fn(hookCtx))
+	}
+
+	return errors.Join(errs...)
+}
+
 func withErrGroupNotifyContext(ctx context.Context) (*errgroup.Group, context.Context, context.CancelFunc) {
 	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	eg, ctx := errgroup.WithContext(ctx)
 	return eg, ctx, cancel
 }
 
-func open(srv *http.Server) error {
-	if srv.TLSConfig != nil {
-		return srv.ListenAndServeTLS("", "")
+func closeAll(lns []net.Listener) {
+	for _, ln := range lns {
+		ln.Close()
+	}
+}
+
+func open(srv *http.Server, ln net.Listener, useTLS bool) error {
+	if useTLS {
+		return srv.ServeTLS(ln, "", "")
 	}
-	return srv.ListenAndServe()
+	return srv.Serve(ln)
 }
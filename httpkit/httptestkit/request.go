@@ -0,0 +1,111 @@
+package httptestkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Request builds a request to a Server. Failures to build or send it fail the test.
+type Request struct {
+	t      testing.TB
+	client *http.Client
+	ctx    context.Context
+	method string
+	url    string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+}
+
+func newRequest(t testing.TB, client *http.Client, method, rawURL string) *Request {
+	return &Request{
+		t:      t,
+		client: client,
+		ctx:    context.Background(),
+		method: method,
+		url:    rawURL,
+		query:  make(url.Values),
+		header: make(http.Header),
+	}
+}
+
+func (r *Request) Context(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Query adds a query parameter, keeping any already present in the path.
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Body sends body with the given content type.
+func (r *Request) Body(body io.Reader, contentType string) *Request {
+	r.body = body
+	r.header.Set("Content-Type", contentType)
+	return r
+}
+
+// JSON sends v encoded as JSON.
+func (r *Request) JSON(v any) *Request {
+	r.t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("encoding request body: %v", err)
+	}
+
+	r.header.Set("Accept", "application/json")
+	return r.Body(bytes.NewReader(b), "application/json")
+}
+
+// Do sends the request and reads the whole response.
+func (r *Request) Do() *Response {
+	r.t.Helper()
+
+	u, err := url.Parse(r.url)
+	if err != nil {
+		r.t.Fatalf("parsing URL %q: %v", r.url, err)
+	}
+
+	if len(r.query) > 0 {
+		q := u.Query()
+		for k, vs := range r.query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, r.method, u.String(), r.body)
+	if err != nil {
+		r.t.Fatalf("building request: %v", err)
+	}
+
+	for k, vs := range r.header {
+		req.Header[k] = vs
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		r.t.Fatalf("reading response of %s %s: %v", req.Method, req.URL.Path, err)
+	}
+
+	return &Response{Response: resp, t: r.t, body: body}
+}
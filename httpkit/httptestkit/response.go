@@ -0,0 +1,121 @@
+package httptestkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response is a response whose body has been read. The Assert methods report failures with t.Errorf and return
+// the response, so they can be chained.
+type Response struct {
+	*http.Response
+	t    testing.TB
+	body []byte
+}
+
+func (r *Response) Bytes() []byte  { return r.body }
+func (r *Response) String() string { return string(r.body) }
+
+// DecodeJSON decodes the response body into v, failing the test if it is not valid JSON.
+func (r *Response) DecodeJSON(v any) {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.body, v); err != nil {
+		r.t.Fatalf("decoding response: %v\nbody: %s", err, r.body)
+	}
+}
+
+func (r *Response) AssertStatus(want int) *Response {
+	r.t.Helper()
+
+	if r.StatusCode != want {
+		r.t.Errorf("status = %d, want %d\nbody: %s", r.StatusCode, want, r.body)
+	}
+	return r
+}
+
+// AssertHeader checks the first value of the header key.
+func (r *Response) AssertHeader(key, want string) *Response {
+	r.t.Helper()
+
+	if got := r.Header.Get(key); got != want {
+		r.t.Errorf("header %s = %q, want %q", key, got, want)
+	}
+	return r
+}
+
+func (r *Response) AssertBodyContains(substr string) *Response {
+	r.t.Helper()
+
+	if !strings.Contains(string(r.body), substr) {
+		r.t.Errorf("body does not contain %q\nbody: %s", substr, r.body)
+	}
+	return r
+}
+
+// AssertJSON checks the value at path in the JSON body, e.g. "items.0.name" for the name of the first item. The
+// empty path refers to the whole body. want is compared as if encoded to JSON and back, so numbers of any type and
+// structs can be given.
+func (r *Response) AssertJSON(path string, want any) *Response {
+	r.t.Helper()
+
+	got, err := r.JSONPath(path)
+	if err != nil {
+		r.t.Errorf("%v\nbody: %s", err, r.body)
+		return r
+	}
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		r.t.Fatalf("encoding expected value: %v", err)
+	}
+	var norm any
+	if err := json.Unmarshal(b, &norm); err != nil {
+		r.t.Fatalf("decoding expected value: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, norm) {
+		gotJSON, _ := json.Marshal(got)
+		r.t.Errorf("JSON at %q = %s, want %s", path, gotJSON, b)
+	}
+	return r
+}
+
+// JSONPath returns the value at path in the JSON body. Path segments are object keys or array indexes separated by
+// dots.
+func (r *Response) JSONPath(path string) (any, error) {
+	var v any
+	if err := json.Unmarshal(r.body, &v); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if path == "" {
+		return v, nil
+	}
+
+	for i, seg := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[seg]
+			if !ok {
+				return nil, fmt.Errorf("JSON path %q: key %q not found", path, seg)
+			}
+			v = next
+		case []any:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("JSON path %q: index %q out of range for array of length %d", path, seg, len(node))
+			}
+			v = node[idx]
+		default:
+			return nil, fmt.Errorf("JSON path %q: segment %d (%q) is not an object or array", path, i, seg)
+		}
+	}
+
+	return v, nil
+}
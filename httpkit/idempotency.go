@@ -0,0 +1,282 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	_idempotencyHeader         = "Idempotency-Key"
+	_defaultIdempotencyTTL     = 24 * time.Hour
+	_defaultIdempotencyLock    = time.Minute
+	_defaultIdempotencyMaxKey  = 255
+	_defaultIdempotencyMaxBody = 1 << 20
+)
+
+var (
+	ErrIdempotencyInProgress = errors.New("idempotency: request with the same key is in progress")
+	ErrIdempotencyMismatch   = errors.New("idempotency: key reused with a different request")
+)
+
+// IdempotencyStore records the first response for each idempotency key.
+type IdempotencyStore interface {
+	// Begin reserves key for a request with the given fingerprint. It returns the stored response if the key has
+	// already completed, ErrIdempotencyInProgress if another request holds the key, or ErrIdempotencyMismatch if
+	// the key was used with a different fingerprint. A nil response and error means the caller owns the key until
+	// the lock timeout passes, after which the key is free again in case the owner died without aborting.
+	Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*CachedResponse, error)
+	// Complete stores the response for a reserved key and keeps it for ttl.
+	Complete(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+	// Abort releases a reserved key without storing a response so the request can be retried.
+	Abort(ctx context.Context, key string) error
+}
+
+type idempotencyConfig struct {
+	ttl         time.Duration
+	lockTimeout time.Duration
+	required    bool
+	scope       func(*http.Request) string
+	maxBody     int64
+}
+
+// Idempotency implements the Idempotency-Key pattern for POST, PUT, PATCH and DELETE requests. The first response
+// for a key is stored and replayed to retries, concurrent duplicates are rejected with 409 Conflict, and reusing a
+// key for a different request is rejected with 422 Unprocessable Entity. Server errors are not stored.
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) Middleware {
+	cfg := idempotencyConfig{
+		ttl:         _defaultIdempotencyTTL,
+		lockTimeout: _defaultIdempotencyLock,
+		maxBody:     _defaultIdempotencyMaxBody,
+	}
+	for _, opt := range opts {
+		opt.applyToIdempotency(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isUnsafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(_idempotencyHeader)
+			if key == "" {
+				if cfg.required {
+					http.Error(w, "missing "+_idempotencyHeader+" header", http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > _defaultIdempotencyMaxKey {
+				http.Error(w, "invalid "+_idempotencyHeader+" header", http.StatusBadRequest)
+				return
+			}
+
+			if cfg.scope != nil {
+				key = cfg.scope(r) + ":" + key
+			}
+
+			fingerprint, err := requestFingerprint(w, r, cfg.maxBody)
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			case err != nil:
+				http.Error(w, "reading request body", http.StatusBadRequest)
+				return
+			}
+
+			resp, err := store.Begin(r.Context(), key, fingerprint, cfg.lockTimeout)
+			switch {
+			case errors.Is(err, ErrIdempotencyInProgress):
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			case errors.Is(err, ErrIdempotencyMismatch):
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			case resp != nil:
+				w.Header().Set("Idempotent-Replayed", "true")
+				copyHeader(w.Header(), resp.Header)
+				w.WriteHeader(resp.Status)
+				_, _ = w.Write(resp.Body)
+				return
+			}
+
+			buf := newResponseBuffer(w, 0)
+			func() {
+				defer func() {
+					if rec := recover(); rec != nil {
+						_ = store.Abort(context.WithoutCancel(r.Context()), key)
+						panic(rec)
+					}
+				}()
+				next.ServeHTTP(buf, r)
+			}()
+
+			ctx := context.WithoutCancel(r.Context())
+
+			if buf.passthrough || buf.statusCode() >= http.StatusInternalServerError {
+				_ = store.Abort(ctx, key)
+			} else {
+				_ = store.Complete(ctx, key, &CachedResponse{
+					Status: buf.statusCode(),
+					Header: buf.header.Clone(),
+					Body:   append([]byte(nil), buf.body.Bytes()...),
+					Stored: time.Now(),
+				}, cfg.ttl)
+			}
+
+			if !buf.passthrough {
+				_ = buf.writeTo(w)
+			}
+		})
+	}
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestFingerprint hashes the method, path and body and restores the body for the handler. Bodies larger than
+// maxBody fail with an *http.MaxBytesError.
+func requestFingerprint(w http.ResponseWriter, r *http.Request, maxBody int64) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			return "", err
+		}
+		_ = r.Body.Close()
+		h.Write(b)
+		r.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type IdempotencyOption interface {
+	applyToIdempotency(*idempotencyConfig)
+}
+
+type IdempotencyOptionFunc func(*idempotencyConfig)
+
+func (f IdempotencyOptionFunc) applyToIdempotency(c *idempotencyConfig) { f(c) }
+
+// WithIdempotencyTTL sets how long completed responses are replayed. The default is 24 hours.
+func WithIdempotencyTTL(d time.Duration) IdempotencyOptionFunc {
+	return func(c *idempotencyConfig) { c.ttl = d }
+}
+
+// WithIdempotencyLockTimeout sets how long a request in progress holds its key, after which a retry may run the
+// request again. It must be longer than the slowest handler. The default is 1 minute.
+func WithIdempotencyLockTimeout(d time.Duration) IdempotencyOptionFunc {
+	return func(c *idempotencyConfig) { c.lockTimeout = d }
+}
+
+// WithIdempotencyRequired rejects unsafe requests without an Idempotency-Key header with 400 Bad Request.
+func WithIdempotencyRequired() IdempotencyOptionFunc {
+	return func(c *idempotencyConfig) { c.required = true }
+}
+
+// WithIdempotencyMaxBody sets the largest request body that is buffered to fingerprint the request. Larger requests
+// are rejected with 413 Request Entity Too Large. The default is 1 MiB.
+func WithIdempotencyMaxBody(n int64) IdempotencyOptionFunc {
+	return func(c *idempotencyConfig) { c.maxBody = n }
+}
+
+// WithIdempotencyScope namespaces keys, typically by the authenticated user, so clients cannot collide.
+func WithIdempotencyScope(fn func(*http.Request) string) IdempotencyOptionFunc {
+	return func(c *idempotencyConfig) { c.scope = fn }
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	resp        *CachedResponse
+	expires     time.Time
+}
+
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]*idempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Begin(_ context.Context, key, fingerprint string, lockTimeout time.Duration) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, ErrIdempotencyMismatch
+		case e.resp == nil:
+			return nil, ErrIdempotencyInProgress
+		default:
+			return e.resp, nil
+		}
+	}
+
+	s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expires: now.Add(lockTimeout)}
+
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.resp = resp
+		e.expires = time.Now().Add(ttl)
+	}
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Abort(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.resp == nil {
+		delete(s.entries, key)
+	}
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) DeleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+type ipFilterConfig struct {
+	allow  []netip.Prefix
+	deny   []netip.Prefix
+	decide func(r *http.Request, addr netip.Addr) bool
+}
+
+// IPFilter answers requests from disallowed client addresses with 403 Forbidden. Addresses are resolved with
+// ClientIP, so put RealIP in front when running behind proxies. Denied prefixes take precedence; when an allow list
+// is given, addresses outside of it are denied as well. Requests without a parseable address are always denied.
+func IPFilter(opts ...IPFilterOption) Middleware {
+	var cfg ipFilterConfig
+	for _, opt := range opts {
+		opt.applyToIPFilter(&cfg)
+	}
+
+	if cfg.decide == nil {
+		cfg.decide = cfg.allowed
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := ClientIP(r)
+			if !addr.IsValid() || !cfg.decide(r, addr) {
+				_ = problem.Write(w, problem.New(http.StatusForbidden).
+					WithDetail("access from your address is not allowed").
+					WithInstance(r.URL.Path))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (cfg *ipFilterConfig) allowed(_ *http.Request, addr netip.Addr) bool {
+	if containsAddr(cfg.deny, addr) {
+		return false
+	}
+	return len(cfg.allow) == 0 || containsAddr(cfg.allow, addr)
+}
+
+type IPFilterOption interface {
+	applyToIPFilter(*ipFilterConfig)
+}
+
+type IPFilterOptionFunc func(*ipFilterConfig)
+
+func (f IPFilterOptionFunc) applyToIPFilter(c *ipFilterConfig) { f(c) }
+
+// WithIPAllow only lets addresses within prefixes through.
+func WithIPAllow(prefixes ...netip.Prefix) IPFilterOptionFunc {
+	return func(c *ipFilterConfig) { c.allow = append(c.allow, prefixes...) }
+}
+
+func WithIPDeny(prefixes ...netip.Prefix) IPFilterOptionFunc {
+	return func(c *ipFilterConfig) { c.deny = append(c.deny, prefixes...) }
+}
+
+// WithIPDecision replaces the allow and deny lists with fn, which reports whether the request may pass.
+func WithIPDecision(fn func(r *http.Request, addr netip.Addr) bool) IPFilterOptionFunc {
+	return func(c *ipFilterConfig) { c.decide = fn }
+}
@@ -0,0 +1,132 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const _defaultMaxBodyBytes = 1 << 20
+
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding response: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+type decodeConfig struct {
+	maxBytes              int64
+	disallowUnknownFields bool
+}
+
+// DecodeJSON decodes the request body into v. Malformed bodies are reported as an *Error with status 400, 413 or
+// 415 and a message describing what is wrong, so handlers can return the error as is. Bodies are limited to 1 MiB
+// unless the route's RouteConfig or an option sets another limit.
+func DecodeJSON(r *http.Request, v any, opts ...DecodeOption) error {
+	cfg := decodeConfig{maxBytes: _defaultMaxBodyBytes}
+	if rc := RouteConfigFromContext(r.Context()); rc != nil && rc.MaxBodyBytes > 0 {
+		cfg.maxBytes = rc.MaxBodyBytes
+	}
+	for _, opt := range opts {
+		opt.applyToDecode(&cfg)
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+			return Errorf(http.StatusUnsupportedMediaType, "content type must be application/json")
+		}
+	}
+
+	if r.Body == nil {
+		return Errorf(http.StatusBadRequest, "request body must not be empty")
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, cfg.maxBytes))
+	if cfg.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeError(err)
+		}
+		return Errorf(http.StatusBadRequest, "request body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+func decodeError(err error) error {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+		invalidErr  *json.InvalidUnmarshalError
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("request body contains badly-formed JSON (at offset %d)", syntaxErr.Offset),
+			Err:     err,
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &Error{Status: http.StatusBadRequest, Message: "request body contains badly-formed JSON", Err: err}
+	case errors.As(err, &typeErr):
+		msg := fmt.Sprintf("request body contains an invalid value (at offset %d)", typeErr.Offset)
+		if typeErr.Field != "" {
+			msg = fmt.Sprintf("request body contains an invalid value for the %q field (at offset %d)", typeErr.Field, typeErr.Offset)
+		}
+		return &Error{Status: http.StatusBadRequest, Message: msg, Err: err}
+	case errors.Is(err, io.EOF):
+		return &Error{Status: http.StatusBadRequest, Message: "request body must not be empty", Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &Error{Status: http.StatusBadRequest, Message: "request body contains unknown field " + field, Err: err}
+	case errors.As(err, &maxBytesErr):
+		return &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit),
+			Err:     err,
+		}
+	case errors.As(err, &invalidErr):
+		// A nil or non-pointer destination is a bug of the handler, not of the request.
+		return NewError(http.StatusInternalServerError, err)
+	default:
+		return err
+	}
+}
+
+type DecodeOption interface {
+	applyToDecode(*decodeConfig)
+}
+
+type DecodeOptionFunc func(*decodeConfig)
+
+func (f DecodeOptionFunc) applyToDecode(c *decodeConfig) { f(c) }
+
+func WithMaxBodyBytes(n int64) DecodeOptionFunc {
+	return func(c *decodeConfig) { c.maxBytes = n }
+}
+
+func WithDisallowUnknownFields() DecodeOptionFunc {
+	return func(c *decodeConfig) { c.disallowUnknownFields = true }
+}
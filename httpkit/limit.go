@@ -0,0 +1,138 @@
+package httpkit
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+const _defaultLimiterRetryAfter = time.Second
+
+// ConcurrencyLimiter bounds the number of requests handled at the same time. Requests beyond the limit wait in a
+// queue, if one is configured, and are answered with 503 Service Unavailable when the queue is full or their wait
+// times out.
+type ConcurrencyLimiter struct {
+	sem          chan struct{}
+	maxQueue     int64
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+	exempt       []func(*http.Request) bool
+
+	inFlight atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Int64
+}
+
+// LimiterStats is a snapshot of a ConcurrencyLimiter. Rejected counts all requests rejected so far.
+type LimiterStats struct {
+	Limit    int
+	InFlight int64
+	Queued   int64
+	Rejected int64
+}
+
+func NewConcurrencyLimiter(limit int, opts ...LimiterOption) *ConcurrencyLimiter {
+	l := ConcurrencyLimiter{sem: make(chan struct{}, max(limit, 1)), retryAfter: _defaultLimiterRetryAfter}
+	for _, opt := range opts {
+		opt.applyToLimiter(&l)
+	}
+	return &l
+}
+
+func (l *ConcurrencyLimiter) Stats() LimiterStats {
+	return LimiterStats{
+		Limit:    cap(l.sem),
+		InFlight: l.inFlight.Load(),
+		Queued:   l.queued.Load(),
+		Rejected: l.rejected.Load(),
+	}
+}
+
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, exempt := range l.exempt {
+			if exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if !l.acquire(r) {
+			if r.Context().Err() != nil {
+				return
+			}
+
+			l.rejected.Add(1)
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(l.retryAfter.Seconds()), 1)))
+			_ = problem.Write(w, problem.New(http.StatusServiceUnavailable).
+				WithDetail("server is at capacity").
+				WithInstance(r.URL.Path))
+			return
+		}
+
+		l.inFlight.Add(1)
+		defer func() {
+			l.inFlight.Add(-1)
+			<-l.sem
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ConcurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queued.Add(1) > l.maxQueue {
+		l.queued.Add(-1)
+		return false
+	}
+	defer l.queued.Add(-1)
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+type LimiterOption interface {
+	applyToLimiter(*ConcurrencyLimiter)
+}
+
+type LimiterOptionFunc func(*ConcurrencyLimiter)
+
+func (f LimiterOptionFunc) applyToLimiter(l *ConcurrencyLimiter) { f(l) }
+
+// WithLimiterQueue lets up to size requests wait for a free slot, each for at most timeout. Zero timeout waits until
+// the client gives up. Without a queue, requests over the limit are rejected immediately.
+func WithLimiterQueue(size int, timeout time.Duration) LimiterOptionFunc {
+	return func(l *ConcurrencyLimiter) { l.maxQueue, l.queueTimeout = int64(size), timeout }
+}
+
+// WithLimiterRetryAfter sets the Retry-After header of rejected requests. The default is 1 second.
+func WithLimiterRetryAfter(d time.Duration) LimiterOptionFunc {
+	return func(l *ConcurrencyLimiter) { l.retryAfter = d }
+}
+
+// WithLimiterExempt lets requests for which fn returns true bypass the limit, e.g. health checks.
+func WithLimiterExempt(fn func(*http.Request) bool) LimiterOptionFunc {
+	return func(l *ConcurrencyLimiter) { l.exempt = append(l.exempt, fn) }
+}
@@ -0,0 +1,207 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultMaintenanceRetryAfter = 5 * time.Minute
+	_defaultMaintenanceMessage    = "service is down for maintenance"
+)
+
+type MaintenanceController struct {
+	mu         sync.RWMutex
+	enabled    bool
+	since      time.Time
+	retryAfter time.Duration
+	message    string
+	exempt     []func(*http.Request) bool
+}
+
+type maintenanceState struct {
+	Enabled    bool       `json:"enabled"`
+	Since      *time.Time `json:"since,omitempty"`
+	RetryAfter string     `json:"retry_after,omitempty"`
+	Message    string     `json:"message,omitempty"`
+}
+
+func NewMaintenanceController(opts ...MaintenanceOption) *MaintenanceController {
+	c := MaintenanceController{retryAfter: _defaultMaintenanceRetryAfter, message: _defaultMaintenanceMessage}
+	for _, opt := range opts {
+		opt.applyToMaintenance(&c)
+	}
+	return &c
+}
+
+func (c *MaintenanceController) Enable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled {
+		c.enabled = true
+		c.since = time.Now()
+	}
+}
+
+func (c *MaintenanceController) Disable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enabled = false
+	c.since = time.Time{}
+}
+
+func (c *MaintenanceController) Enabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.enabled
+}
+
+// While enables maintenance mode for the duration of fn, e.g. around pgxkit migrations.
+func (c *MaintenanceController) While(fn func() error) error {
+	c.Enable()
+	defer c.Disable()
+	return fn()
+}
+
+func (c *MaintenanceController) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := c.state()
+		if !state.Enabled || c.isExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.mu.RLock()
+		retryAfter := c.retryAfter
+		c.mu.RUnlock()
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		http.Error(w, state.Message, http.StatusServiceUnavailable)
+	})
+}
+
+// Handler is an admin endpoint reporting the current state on GET, enabling maintenance mode on POST or PUT and
+// disabling it on DELETE. POST and PUT accept an optional JSON body with "retry_after" (a Go duration) and "message".
+func (c *MaintenanceController) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPut:
+			var req maintenanceState
+			if r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "invalid request body", http.StatusBadRequest)
+					return
+				}
+			}
+			if err := c.update(req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.Enable()
+		case http.MethodDelete:
+			c.Disable()
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PUT, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.state())
+	})
+}
+
+func (c *MaintenanceController) update(req maintenanceState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.RetryAfter != "" {
+		d, err := time.ParseDuration(req.RetryAfter)
+		if err != nil {
+			return err
+		}
+		c.retryAfter = d
+	}
+
+	if req.Message != "" {
+		c.message = req.Message
+	}
+
+	return nil
+}
+
+func (c *MaintenanceController) state() maintenanceState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state := maintenanceState{
+		Enabled:    c.enabled,
+		RetryAfter: c.retryAfter.String(),
+		Message:    c.message,
+	}
+
+	if c.enabled {
+		since := c.since
+		state.Since = &since
+	}
+
+	return state
+}
+
+func (c *MaintenanceController) isExempt(r *http.Request) bool {
+	for _, fn := range c.exempt {
+		if fn(r) {
+			return true
+		}
+	}
+	return false
+}
+
+type MaintenanceOption interface {
+	applyToMaintenance(*MaintenanceController)
+}
+
+type MaintenanceOptionFunc func(*MaintenanceController)
+
+func (f MaintenanceOptionFunc) applyToMaintenance(c *MaintenanceController) { f(c) }
+
+func WithMaintenanceRetryAfter(d time.Duration) MaintenanceOptionFunc {
+	return func(c *MaintenanceController) { c.retryAfter = d }
+}
+
+func WithMaintenanceMessage(msg string) MaintenanceOptionFunc {
+	return func(c *MaintenanceController) { c.message = msg }
+}
+
+// WithMaintenanceExemptPaths lets requests whose path starts with one of prefixes through, e.g. health checks.
+func WithMaintenanceExemptPaths(prefixes ...string) MaintenanceOptionFunc {
+	return func(c *MaintenanceController) {
+		c.exempt = append(c.exempt, func(r *http.Request) bool {
+			for _, p := range prefixes {
+				if strings.HasPrefix(r.URL.Path, p) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+func WithMaintenanceExempt(fn func(*http.Request) bool) MaintenanceOptionFunc {
+	return func(c *MaintenanceController) { c.exempt = append(c.exempt, fn) }
+}
+
+// WithMaintenanceEnabled starts the controller in maintenance mode.
+func WithMaintenanceEnabled() MaintenanceOptionFunc {
+	return func(c *MaintenanceController) {
+		c.enabled = true
+		c.since = time.Now()
+	}
+}
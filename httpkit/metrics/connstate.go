@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ConnState creates the connection metrics and returns a hook for httpkit.WithConnState maintaining them. A gauge
+// labeled by state counts the new, active and idle connections; accepted, closed and hijacked connections are
+// counted in total, as they leave the server's hands.
+func ConnState(opts ...Option) (func(net.Conn, http.ConnState), error) {
+	cfg := config{registerer: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	open, err := register(cfg.registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: cfg.namespace,
+		Subsystem: "http",
+		Name:      "connections",
+		Help:      "Number of open HTTP connections by state.",
+	}, []string{"state"}))
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := register(cfg.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: cfg.namespace,
+		Subsystem: "http",
+		Name:      "connections_total",
+		Help:      "Total number of HTTP connections by event: accepted, closed or hijacked.",
+	}, []string{"event"}))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu     sync.Mutex
+		states = make(map[net.Conn]http.ConnState)
+	)
+
+	return func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if prev, ok := states[c]; ok {
+			open.WithLabelValues(prev.String()).Dec()
+		}
+
+		switch state {
+		case http.StateNew:
+			total.WithLabelValues("accepted").Inc()
+		case http.StateClosed:
+			total.WithLabelValues("closed").Inc()
+			delete(states, c)
+			return
+		case http.StateHijacked:
+			total.WithLabelValues("hijacked").Inc()
+			delete(states, c)
+			return
+		}
+
+		states[c] = state
+		open.WithLabelValues(state.String()).Inc()
+	}, nil
+}
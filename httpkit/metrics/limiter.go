@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/drakelthedragon/toolbox/httpkit"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterLimiter exposes the in-flight and queued requests and the rejections of l. Name distinguishes several
+// limiters through the "limiter" label.
+func RegisterLimiter(l *httpkit.ConcurrencyLimiter, name string, opts ...Option) error {
+	cfg := config{registerer: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	labels := prometheus.Labels{"limiter": name}
+
+	collectors := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   "http",
+			Name:        "limiter_in_flight",
+			Help:        "Number of requests holding a slot of the concurrency limiter.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(l.Stats().InFlight) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   "http",
+			Name:        "limiter_queued",
+			Help:        "Number of requests waiting for a slot of the concurrency limiter.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(l.Stats().Queued) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   "http",
+			Name:        "limiter_rejected_total",
+			Help:        "Total number of requests rejected by the concurrency limiter.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(l.Stats().Rejected) }),
+	}
+
+	for _, c := range collectors {
+		if err := cfg.registerer.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const _unmatchedRoute = "unmatched"
+
+var (
+	_defaultDurationBuckets = prometheus.DefBuckets
+	_defaultSizeBuckets     = prometheus.ExponentialBuckets(100, 10, 7)
+)
+
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	size     *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	route    func(*http.Request) string
+}
+
+type config struct {
+	registerer      prometheus.Registerer
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+	route           func(*http.Request) string
+}
+
+// New creates the HTTP server metrics and registers them. Registering twice with the same registerer reuses the
+// already registered collectors, so New is safe to call for several servers sharing a registry.
+func New(opts ...Option) (*Metrics, error) {
+	cfg := config{
+		registerer:      prometheus.DefaultRegisterer,
+		durationBuckets: _defaultDurationBuckets,
+		sizeBuckets:     _defaultSizeBuckets,
+		route:           defaultRoute,
+	}
+
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	labels := []string{"method", "route", "status"}
+
+	m := Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests handled.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests.",
+			Buckets:   cfg.durationBuckets,
+		}, labels),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "Size of HTTP response bodies.",
+			Buckets:   cfg.sizeBuckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being handled.",
+		}),
+		route: cfg.route,
+	}
+
+	var err error
+
+	if m.requests, err = register(cfg.registerer, m.requests); err != nil {
+		return nil, err
+	}
+
+	if m.duration, err = register(cfg.registerer, m.duration); err != nil {
+		return nil, err
+	}
+
+	if m.size, err = register(cfg.registerer, m.size); err != nil {
+		return nil, err
+	}
+
+	if m.inFlight, err = register(cfg.registerer, m.inFlight); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		rw := httpkit.WrapResponseWriter(w)
+		r = httpkit.CaptureRoute(r)
+
+		next.ServeHTTP(rw, r)
+
+		labels := prometheus.Labels{
+			"method": r.Method,
+			"route":  m.route(r),
+			"status": strconv.Itoa(rw.Status()),
+		}
+
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+		m.size.With(labels).Observe(float64(rw.BytesWritten()))
+	})
+}
+
+// Handler serves the metrics gathered by g in the Prometheus exposition format. A nil g uses the default gatherer.
+func Handler(g prometheus.Gatherer) http.Handler {
+	if g == nil {
+		g = prometheus.DefaultGatherer
+	}
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+func defaultRoute(r *http.Request) string {
+	if pattern := httpkit.RoutePattern(r); pattern != "" {
+		return pattern
+	}
+	return _unmatchedRoute
+}
+
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+type Option interface {
+	applyToConfig(*config)
+}
+
+type OptionFunc func(*config)
+
+func (f OptionFunc) applyToConfig(c *config) { f(c) }
+
+func WithRegisterer(reg prometheus.Registerer) OptionFunc {
+	return func(c *config) { c.registerer = reg }
+}
+
+func WithNamespace(ns string) OptionFunc {
+	return func(c *config) { c.namespace = ns }
+}
+
+func WithDurationBuckets(buckets ...float64) OptionFunc {
+	return func(c *config) { c.durationBuckets = buckets }
+}
+
+func WithSizeBuckets(buckets ...float64) OptionFunc {
+	return func(c *config) { c.sizeBuckets = buckets }
+}
+
+// WithRouteFunc sets how the route label is derived. It is called after the handler ran and must return a value of
+// bounded cardinality, such as the matched pattern, never the raw path.
+func WithRouteFunc(fn func(*http.Request) string) OptionFunc {
+	return func(c *config) { c.route = fn }
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// _transportError is the status label of requests that failed without a response.
+const _transportError = "error"
+
+// Transport creates the outbound request metrics, labeled by host, method and status, and returns a client
+// middleware recording them. The route option does not apply to outbound requests.
+func Transport(opts ...Option) (client.Middleware, error) {
+	cfg := config{
+		registerer:      prometheus.DefaultRegisterer,
+		durationBuckets: _defaultDurationBuckets,
+	}
+
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	labels := []string{"host", "method", "status"}
+
+	requests, err := register(cfg.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: cfg.namespace,
+		Subsystem: "http_client",
+		Name:      "requests_total",
+		Help:      "Total number of outbound HTTP requests.",
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := register(cfg.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: cfg.namespace,
+		Subsystem: "http_client",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of outbound HTTP requests until the response headers arrived.",
+		Buckets:   cfg.durationBuckets,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+
+	inFlight, err := register(cfg.registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: cfg.namespace,
+		Subsystem: "http_client",
+		Name:      "requests_in_flight",
+		Help:      "Number of outbound HTTP requests waiting for a response.",
+	}, []string{"host"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+
+			inFlight.WithLabelValues(host).Inc()
+			defer inFlight.WithLabelValues(host).Dec()
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			status := _transportError
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+
+			l := prometheus.Labels{"host": host, "method": req.Method, "status": status}
+			requests.With(l).Inc()
+			duration.With(l).Observe(time.Since(start).Seconds())
+
+			return resp, err
+		})
+	}, nil
+}
@@ -0,0 +1,15 @@
+package httpkit
+
+import "net/http"
+
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares so that the first one is the outermost.
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		return h
+	}
+}
@@ -0,0 +1,173 @@
+package httpkit
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type acceptRange struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses an Accept-style header into its values and quality factors. Values with an invalid q are
+// skipped.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		ar := acceptRange{value: value, q: 1}
+
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				q, err := strconv.ParseFloat(v, 64)
+				if err != nil || q < 0 || q > 1 {
+					q = -1
+				}
+				ar.q = q
+			}
+		}
+
+		if ar.q >= 0 {
+			ranges = append(ranges, ar)
+		}
+	}
+
+	return ranges
+}
+
+// mediaQuality returns the quality of the most specific range in ranges matching the media type offer.
+func mediaQuality(ranges []acceptRange, offer string) float64 {
+	typ, sub, _ := strings.Cut(strings.ToLower(offer), "/")
+	q, specificity := 0.0, -1
+
+	for _, ar := range ranges {
+		rt, rs, _ := strings.Cut(ar.value, "/")
+
+		var s int
+		switch {
+		case rt == typ && rs == sub:
+			s = 2
+		case rt == typ && rs == "*":
+			s = 1
+		case rt == "*" && rs == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+
+	return q
+}
+
+// acceptsCharset reports whether the Accept-Charset header of r allows charset.
+func acceptsCharset(r *http.Request, charset string) bool {
+	header := r.Header.Get("Accept-Charset")
+	if header == "" {
+		return true
+	}
+
+	q, found := 0.0, false
+	for _, ar := range parseAccept(header) {
+		switch ar.value {
+		case strings.ToLower(charset):
+			return ar.q > 0
+		case "*":
+			q, found = ar.q, true
+		}
+	}
+
+	return found && q > 0
+}
+
+// Negotiate returns the offered media type the client prefers according to the q-values of its Accept header, or ""
+// if none is acceptable. Ties are broken by the order of offers, and a missing Accept header accepts the first
+// offer. Since responses are always encoded as UTF-8, nothing is acceptable if Accept-Charset excludes it.
+func Negotiate(r *http.Request, offers ...string) string {
+	if len(offers) == 0 || !acceptsCharset(r, "utf-8") {
+		return ""
+	}
+
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(header)
+	best, bestQ := "", 0.0
+
+	for _, offer := range offers {
+		if q := mediaQuality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// Respond writes v with status in the representation negotiated from the request: JSON, XML or plain text. Plain
+// text uses v's MarshalText or String method if it has one. If the client accepts none of them, a 406 *Error is
+// returned and nothing is written.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	w.Header().Add("Vary", "Accept")
+
+	var (
+		b   []byte
+		err error
+	)
+
+	mt := Negotiate(r, "application/json", "application/xml", "text/xml", "text/plain")
+	switch mt {
+	case "application/json":
+		return WriteJSON(w, status, v)
+	case "application/xml", "text/xml":
+		b, err = xml.Marshal(v)
+		b = append([]byte(xml.Header), b...)
+	case "text/plain":
+		b, err = marshalText(v)
+	default:
+		return Errorf(http.StatusNotAcceptable, "response is only available as JSON, XML or plain text")
+	}
+
+	if err != nil {
+		return fmt.Errorf("encoding response: %w", err)
+	}
+
+	w.Header().Set("Content-Type", mt+"; charset=utf-8")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func marshalText(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	case fmt.Stringer:
+		return []byte(v.String()), nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case error:
+		return []byte(v.Error()), nil
+	}
+
+	return []byte(fmt.Sprint(v)), nil
+}
@@ -0,0 +1,124 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit"
+	"github.com/drakelthedragon/toolbox/pgxkit"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	_defaultIdempotencyTable  = "idempotency_keys"
+	_idempotencyBeginAttempts = 3
+)
+
+// IdempotencySchema creates the table used by IdempotencyStore with its default name.
+const IdempotencySchema = `CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key         TEXT PRIMARY KEY,
+	fingerprint TEXT NOT NULL,
+	status      INTEGER,
+	header      JSONB,
+	body        BYTEA,
+	created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);`
+
+// IdempotencyStore is an httpkit.IdempotencyStore in a PostgreSQL table.
+type IdempotencyStore struct {
+	db    DB
+	table string
+}
+
+type idempotencyRow struct {
+	Fingerprint string      `db:"fingerprint"`
+	Status      *int        `db:"status"`
+	Header      http.Header `db:"header"`
+	Body        []byte      `db:"body"`
+	CreatedAt   time.Time   `db:"created_at"`
+}
+
+func NewIdempotencyStore(db DB, opts ...IdempotencyStoreOption) *IdempotencyStore {
+	s := IdempotencyStore{db: db, table: _defaultIdempotencyTable}
+	for _, opt := range opts {
+		opt.applyToIdempotencyStore(&s)
+	}
+	s.table = pgx.Identifier(strings.Split(s.table, ".")).Sanitize()
+	return &s
+}
+
+func (s *IdempotencyStore) Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*httpkit.CachedResponse, error) {
+	insert := "INSERT INTO " + s.table + " AS t (key, fingerprint, expires_at) VALUES ($1, $2, $3) " +
+		"ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, expires_at = EXCLUDED.expires_at, " +
+		"status = NULL, header = NULL, body = NULL, created_at = now() WHERE t.expires_at <= now() RETURNING true"
+
+	// The key held by another request may be released by Abort between the insert and the select, in which case the
+	// insert is tried again. A key that keeps changing hands is reported as in progress.
+	for range _idempotencyBeginAttempts {
+		_, err := pgxkit.QueryValue[bool](ctx, s.db, insert, key, fingerprint, time.Now().Add(lockTimeout))
+		switch {
+		case err == nil:
+			return nil, nil
+		case !errors.Is(err, pgxkit.ErrNotFound):
+			return nil, err
+		}
+
+		row, err := pgxkit.QueryRow[idempotencyRow](ctx, s.db,
+			"SELECT fingerprint, status, header, body, created_at FROM "+s.table+" WHERE key = $1", key)
+		switch {
+		case errors.Is(err, pgxkit.ErrNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		return row.response(fingerprint)
+	}
+
+	return nil, httpkit.ErrIdempotencyInProgress
+}
+
+func (row *idempotencyRow) response(fingerprint string) (*httpkit.CachedResponse, error) {
+	switch {
+	case row.Fingerprint != fingerprint:
+		return nil, httpkit.ErrIdempotencyMismatch
+	case row.Status == nil:
+		return nil, httpkit.ErrIdempotencyInProgress
+	default:
+		return &httpkit.CachedResponse{Status: *row.Status, Header: row.Header, Body: row.Body, Stored: row.CreatedAt}, nil
+	}
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, resp *httpkit.CachedResponse, ttl time.Duration) error {
+	return pgxkit.Exec(ctx, s.db,
+		"UPDATE "+s.table+" SET status = $2, header = $3, body = $4, expires_at = $5 WHERE key = $1",
+		key, resp.Status, resp.Header, resp.Body, time.Now().Add(ttl))
+}
+
+func (s *IdempotencyStore) Abort(ctx context.Context, key string) error {
+	return pgxkit.Exec(ctx, s.db, "DELETE FROM "+s.table+" WHERE key = $1 AND status IS NULL", key)
+}
+
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context) error {
+	return pgxkit.Exec(ctx, s.db, "DELETE FROM "+s.table+" WHERE expires_at <= now()")
+}
+
+type IdempotencyStoreOption interface {
+	applyToIdempotencyStore(*IdempotencyStore)
+}
+
+type IdempotencyStoreOptionFunc func(*IdempotencyStore)
+
+func (f IdempotencyStoreOptionFunc) applyToIdempotencyStore(s *IdempotencyStore) {
+	f(s)
+}
+
+func WithIdempotencyTable(name string) IdempotencyStoreOptionFunc {
+	return func(s *IdempotencyStore) { s.table = name }
+}
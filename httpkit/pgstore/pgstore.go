@@ -0,0 +1,26 @@
+// Package pgstore implements the stores of httpkit and its subpackages on PostgreSQL through pgxkit, so httpkit
+// itself does not depend on a database driver.
+package pgstore
+
+import (
+	"net/http"
+
+	"github.com/drakelthedragon/toolbox/httpkit"
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+// DB is what the stores run their queries on, e.g. a pgxkit.Client or a transaction.
+type DB interface {
+	pgxkit.Queryer
+	pgxkit.Execer
+}
+
+// ErrorMappings maps the errors of pgxkit to status codes: not found to 404, already exists to 409 and statement
+// timeouts to 504. Canceled queries wrap context.Canceled, which the ErrorHandler maps to 499 itself.
+func ErrorMappings() httpkit.ErrorHandlerOptionFunc {
+	return func(h *httpkit.ErrorHandler) {
+		httpkit.WithErrorStatus(pgxkit.ErrTimeout, http.StatusGatewayTimeout)(h)
+		httpkit.WithErrorStatus(pgxkit.ErrAlreadyExists, http.StatusConflict)(h)
+		httpkit.WithErrorStatus(pgxkit.ErrNotFound, http.StatusNotFound)(h)
+	}
+}
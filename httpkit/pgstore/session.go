@@ -0,0 +1,77 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+	"github.com/jackc/pgx/v5"
+)
+
+const _defaultSessionTable = "sessions"
+
+// SessionSchema creates the table used by SessionStore with its default name.
+const SessionSchema = `CREATE TABLE IF NOT EXISTS sessions (
+	token  TEXT PRIMARY KEY,
+	data   BYTEA NOT NULL,
+	expiry TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions (expiry);`
+
+// SessionStore is a session.Store in a PostgreSQL table.
+type SessionStore struct {
+	db    DB
+	table string
+}
+
+func NewSessionStore(db DB, opts ...SessionStoreOption) *SessionStore {
+	s := SessionStore{db: db, table: _defaultSessionTable}
+	for _, opt := range opts {
+		opt.applyToSessionStore(&s)
+	}
+	s.table = pgx.Identifier(strings.Split(s.table, ".")).Sanitize()
+	return &s
+}
+
+func (s *SessionStore) Find(ctx context.Context, token string) ([]byte, bool, error) {
+	sql := "SELECT data FROM " + s.table + " WHERE token = $1 AND expiry > now()"
+
+	b, err := pgxkit.QueryValue[[]byte](ctx, s.db, sql, token)
+	switch {
+	case errors.Is(err, pgxkit.ErrNotFound):
+		return nil, false, nil
+	case err != nil:
+		return nil, false, err
+	default:
+		return b, true, nil
+	}
+}
+
+func (s *SessionStore) Commit(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	sql := "INSERT INTO " + s.table + " (token, data, expiry) VALUES ($1, $2, $3) " +
+		"ON CONFLICT (token) DO UPDATE SET data = EXCLUDED.data, expiry = EXCLUDED.expiry"
+	return pgxkit.Exec(ctx, s.db, sql, token, b, expiry)
+}
+
+func (s *SessionStore) Delete(ctx context.Context, token string) error {
+	return pgxkit.Exec(ctx, s.db, "DELETE FROM "+s.table+" WHERE token = $1", token)
+}
+
+func (s *SessionStore) DeleteExpired(ctx context.Context) error {
+	return pgxkit.Exec(ctx, s.db, "DELETE FROM "+s.table+" WHERE expiry <= now()")
+}
+
+type SessionStoreOption interface {
+	applyToSessionStore(*SessionStore)
+}
+
+type SessionStoreOptionFunc func(*SessionStore)
+
+func (f SessionStoreOptionFunc) applyToSessionStore(s *SessionStore) { f(s) }
+
+func WithSessionTable(name string) SessionStoreOptionFunc {
+	return func(s *SessionStore) { s.table = name }
+}
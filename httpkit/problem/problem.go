@@ -0,0 +1,159 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	ContentType = "application/problem+json"
+	BlankType   = "about:blank"
+)
+
+// Details is an RFC 9457 problem details document. It implements error so handlers can return it directly.
+type Details struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// New creates a problem of the generic "about:blank" type titled after the status code.
+func New(status int) *Details {
+	return &Details{Type: BlankType, Title: http.StatusText(status), Status: status}
+}
+
+func (d *Details) WithType(uri, title string) *Details {
+	d.Type, d.Title = uri, title
+	return d
+}
+
+func (d *Details) WithDetail(detail string) *Details {
+	d.Detail = detail
+	return d
+}
+
+func (d *Details) WithInstance(instance string) *Details {
+	d.Instance = instance
+	return d
+}
+
+// With sets an extension member. Members named after the standard ones are ignored when encoding.
+func (d *Details) With(key string, value any) *Details {
+	if d.Extensions == nil {
+		d.Extensions = make(map[string]any)
+	}
+	d.Extensions[key] = value
+	return d
+}
+
+func (d *Details) Clone() *Details {
+	c := *d
+	if d.Extensions != nil {
+		c.Extensions = make(map[string]any, len(d.Extensions))
+		for k, v := range d.Extensions {
+			c.Extensions[k] = v
+		}
+	}
+	return &c
+}
+
+func (d *Details) Error() string {
+	if d.Detail != "" {
+		return d.Title + ": " + d.Detail
+	}
+	return d.Title
+}
+
+func (d *Details) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(d.Extensions)+5)
+	for k, v := range d.Extensions {
+		m[k] = v
+	}
+
+	m["type"] = d.Type
+	if d.Type == "" {
+		m["type"] = BlankType
+	}
+
+	if d.Title != "" {
+		m["title"] = d.Title
+	} else {
+		delete(m, "title")
+	}
+
+	if d.Status != 0 {
+		m["status"] = d.Status
+	} else {
+		delete(m, "status")
+	}
+
+	if d.Detail != "" {
+		m["detail"] = d.Detail
+	} else {
+		delete(m, "detail")
+	}
+
+	if d.Instance != "" {
+		m["instance"] = d.Instance
+	} else {
+		delete(m, "instance")
+	}
+
+	return json.Marshal(m)
+}
+
+func (d *Details) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*d = Details{}
+
+	members := map[string]any{
+		"type":     &d.Type,
+		"title":    &d.Title,
+		"status":   &d.Status,
+		"detail":   &d.Detail,
+		"instance": &d.Instance,
+	}
+
+	for k, raw := range m {
+		if dst, ok := members[k]; ok {
+			// Members of the wrong type are ignored as required by the RFC.
+			_ = json.Unmarshal(raw, dst)
+			continue
+		}
+
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		d.With(k, v)
+	}
+
+	return nil
+}
+
+// Write sends d with the problem+json content type and d.Status as status code.
+func Write(w http.ResponseWriter, d *Details) error {
+	status := d.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,236 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+// ForwardedPolicy controls how the X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers are sent
+// upstream.
+type ForwardedPolicy int
+
+const (
+	// ForwardedReplace discards the headers sent by the client and sets them from the incoming request.
+	ForwardedReplace ForwardedPolicy = iota
+	// ForwardedAppend keeps the X-Forwarded-For chain sent by the client and appends the client address. Only use it
+	// behind a proxy that sets the header itself.
+	ForwardedAppend
+	// ForwardedNone sends no X-Forwarded headers.
+	ForwardedNone
+)
+
+type proxyConfig struct {
+	stripPrefix  string
+	rewritePath  func(string) string
+	host         string
+	preserveHost bool
+	forwarded    ForwardedPolicy
+	timeout      time.Duration
+	retries      int
+	transport    http.RoundTripper
+	log          *slog.Logger
+}
+
+// ReverseProxy forwards requests to target. Upstream failures are answered with problem details: 504 Gateway
+// Timeout when the upstream did not respond in time and 502 Bad Gateway otherwise.
+func ReverseProxy(target *url.URL, opts ...ProxyOption) http.Handler {
+	cfg := proxyConfig{transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt.applyToProxy(&cfg)
+	}
+
+	transport := cfg.transport
+	if cfg.retries > 0 {
+		transport = &retryTransport{next: transport, retries: cfg.retries}
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			path := pr.In.URL.Path
+			if cfg.stripPrefix != "" {
+				path = stripPathPrefix(path, cfg.stripPrefix)
+			}
+			if cfg.rewritePath != nil {
+				path = cfg.rewritePath(path)
+			}
+			pr.Out.URL.Path, pr.Out.URL.RawPath = path, ""
+
+			pr.SetURL(target)
+
+			switch {
+			case cfg.host != "":
+				pr.Out.Host = cfg.host
+			case cfg.preserveHost:
+				pr.Out.Host = pr.In.Host
+			}
+
+			switch cfg.forwarded {
+			case ForwardedAppend:
+				pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+				pr.SetXForwarded()
+			case ForwardedReplace:
+				pr.SetXForwarded()
+			}
+		},
+		Transport:    transport,
+		ErrorHandler: cfg.handleError,
+	}
+
+	if cfg.timeout <= 0 {
+		return proxy
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), cfg.timeout)
+		defer cancel()
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (cfg *proxyConfig) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		// The client went away, there is nobody to respond to.
+		return
+	}
+
+	status := http.StatusBadGateway
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		status = http.StatusGatewayTimeout
+	}
+
+	if cfg.log != nil {
+		cfg.log.ErrorContext(r.Context(), "proxying request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Group("error", slog.String("msg", err.Error())),
+		)
+	}
+
+	_ = problem.Write(w, problem.New(status).WithDetail("upstream service is unavailable").WithInstance(r.URL.Path))
+}
+
+// retryTransport retries idempotent requests without a body when the upstream cannot be reached or answers with
+// 502, 503 or 504.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt == t.retries || req.Context().Err() != nil || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(attempt+1) * 50 * time.Millisecond):
+		}
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 && req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+type ProxyOption interface {
+	applyToProxy(*proxyConfig)
+}
+
+type ProxyOptionFunc func(*proxyConfig)
+
+func (f ProxyOptionFunc) applyToProxy(c *proxyConfig) { f(c) }
+
+// stripPathPrefix removes prefix from path if it ends at a segment boundary, so "/api" is stripped from "/api" and
+// "/api/users" but not from "/apiary".
+func stripPathPrefix(path, prefix string) string {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok || rest != "" && rest[0] != '/' {
+		return path
+	}
+	return "/" + strings.TrimPrefix(rest, "/")
+}
+
+// WithProxyStripPrefix removes prefix from the request path before it is joined with the target path. Only whole
+// path segments are stripped.
+func WithProxyStripPrefix(prefix string) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.stripPrefix = strings.TrimSuffix(prefix, "/") }
+}
+
+// WithProxyRewritePath rewrites the request path before it is joined with the target path, after any prefix is
+// stripped.
+func WithProxyRewritePath(fn func(path string) string) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.rewritePath = fn }
+}
+
+// WithProxyHost sets the Host header sent upstream. By default it is the target host.
+func WithProxyHost(host string) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.host = host }
+}
+
+// WithProxyPreserveHost sends the Host header of the incoming request upstream.
+func WithProxyPreserveHost() ProxyOptionFunc {
+	return func(c *proxyConfig) { c.preserveHost = true }
+}
+
+func WithProxyForwarded(policy ForwardedPolicy) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.forwarded = policy }
+}
+
+// WithProxyTimeout limits how long a request to the upstream, including reading its response, may take.
+func WithProxyTimeout(d time.Duration) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.timeout = d }
+}
+
+// WithProxyRetries retries idempotent requests without a body up to n times when the upstream fails.
+func WithProxyRetries(n int) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.retries = n }
+}
+
+func WithProxyTransport(rt http.RoundTripper) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.transport = rt }
+}
+
+// WithProxyLogger logs failed upstream requests.
+func WithProxyLogger(log *slog.Logger) ProxyOptionFunc {
+	return func(c *proxyConfig) { c.log = log }
+}
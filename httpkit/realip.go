@@ -0,0 +1,111 @@
+package httpkit
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var clientIPKey = NewContextKey[netip.Addr]("client ip")
+
+// RealIP resolves the client address of requests arriving through the proxies in trusted. Only when the direct peer
+// is trusted, X-Forwarded-For is walked from the right and the first address not in trusted is taken, so clients
+// cannot spoof their address by sending the header themselves. Read the result with ClientIP.
+func RealIP(trusted ...netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := remoteAddr(r)
+			if addr.IsValid() && containsAddr(trusted, addr) {
+				addr = forwardedFor(r, trusted, addr)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextSet(r.Context(), clientIPKey, addr)))
+		})
+	}
+}
+
+// ClientIP returns the client address resolved by RealIP, or the address of the direct peer without it. The result
+// is invalid if the address cannot be parsed.
+func ClientIP(r *http.Request) netip.Addr {
+	if addr, ok := ContextGet(r.Context(), clientIPKey); ok {
+		return addr
+	}
+	return remoteAddr(r)
+}
+
+// ParsePrefixes parses CIDR prefixes such as "10.0.0.0/8". Single addresses are taken as prefixes of full length.
+func ParsePrefixes(s ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(s))
+	for _, v := range s {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// MustParsePrefixes is ParsePrefixes panicking on error, for prefixes fixed at compile time.
+func MustParsePrefixes(s ...string) []netip.Prefix {
+	prefixes, err := ParsePrefixes(s...)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+func remoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func forwardedFor(r *http.Request, trusted []netip.Prefix, peer netip.Addr) netip.Addr {
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	addr := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Everything left of a malformed entry cannot be trusted.
+			return addr
+		}
+
+		addr = hop.Unmap()
+		if !containsAddr(trusted, addr) {
+			return addr
+		}
+	}
+
+	return addr
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package httpkit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is the error a recovered panic is handled as. It deliberately does not unwrap to a panic value that is
+// an error, so panics are always answered with 500 Internal Server Error.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// Recover recovers panics of next and handles them as a *PanicError with DefaultErrorHandler.
+func Recover(next http.Handler) http.Handler { return DefaultErrorHandler.Recover(next) }
+
+// Recover recovers panics of next and handles them as a *PanicError, answering 500 Internal Server Error unless the
+// response was already written, in which case the panic is only logged. http.ErrAbortHandler is passed on so the
+// server aborts the response as intended.
+func (h *ErrorHandler) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := WrapResponseWriter(w)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+			h.HandleError(rw, r, &PanicError{Value: rec, Stack: debug.Stack()})
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,40 @@
+package httpkit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const _redirectTimeout = 5 * time.Second
+
+// redirectServer answers plaintext requests on addr with a permanent redirect to the HTTPS server listening on
+// httpsPort. When acme is set, it wraps the redirect handler so ACME HTTP-01 challenges are answered first.
+func redirectServer(addr string, httpsPort int, acme func(fallback http.Handler) http.Handler) *http.Server {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		w.Header().Set("Connection", "close")
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+
+	if acme != nil {
+		h = acme(h)
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: _redirectTimeout,
+		ReadTimeout:       _redirectTimeout,
+		WriteTimeout:      _redirectTimeout,
+		IdleTimeout:       _redirectTimeout,
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !unix || solaris || illumos
+
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+func listen(cfg *Config) ([]net.Listener, error) {
+	if cfg.reusePort {
+		return nil, errors.New("SO_REUSEPORT is not supported on this platform")
+	}
+
+	lns := make([]net.Listener, 0, len(cfg.listenAddrs()))
+	for _, addr := range cfg.listenAddrs() {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			closeAll(lns)
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+func watchRestart(ctx context.Context, _ []net.Listener, _ *Config, _ func()) error {
+	<-ctx.Done()
+	return nil
+}
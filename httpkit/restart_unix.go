@@ -0,0 +1,161 @@
+//go:build unix && !solaris && !illumos
+
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// _listenerFDEnv names the environment variable telling a restarted process which file descriptors, separated by
+// commas and in the order of Config.listenAddrs, hold the listeners inherited from its parent.
+const _listenerFDEnv = "HTTPKIT_LISTENER_FDS"
+
+// listen creates a listener for every address in cfg.listenAddrs, or takes over the ones inherited from the parent
+// process after a graceful restart.
+func listen(cfg *Config) ([]net.Listener, error) {
+	if v := os.Getenv(_listenerFDEnv); v != "" {
+		os.Unsetenv(_listenerFDEnv)
+
+		lns, err := inherit(v)
+		if err == nil && len(lns) != len(cfg.listenAddrs()) {
+			closeAll(lns)
+			return nil, fmt.Errorf("inherited %d listeners for %d addresses", len(lns), len(cfg.listenAddrs()))
+		}
+		return lns, err
+	}
+
+	var lc net.ListenConfig
+	if cfg.reusePort {
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}); err != nil {
+				return err
+			}
+			return serr
+		}
+	}
+
+	lns := make([]net.Listener, 0, len(cfg.listenAddrs()))
+	for _, addr := range cfg.listenAddrs() {
+		ln, err := lc.Listen(context.Background(), "tcp", addr)
+		if err != nil {
+			closeAll(lns)
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+func inherit(fds string) ([]net.Listener, error) {
+	var lns []net.Listener
+	for _, v := range strings.Split(fds, ",") {
+		fd, err := strconv.Atoi(v)
+		if err != nil {
+			closeAll(lns)
+			return nil, fmt.Errorf("invalid %s %q: %w", _listenerFDEnv, fds, err)
+		}
+
+		f := os.NewFile(uintptr(fd), "listener")
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			closeAll(lns)
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+// watchRestart starts a new instance of the executable sharing lns whenever SIGUSR2 is received, then calls stop so
+// this instance drains and exits. Connections queue on the shared socket until the new instance accepts them.
+func watchRestart(ctx context.Context, lns []net.Listener, cfg *Config, stop func()) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR2)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sig:
+		}
+
+		if err := spawn(lns, cfg); err != nil {
+			if cfg.ErrorLog != nil {
+				cfg.ErrorLog.Printf("httpkit: restarting: %v", err)
+			}
+			continue
+		}
+
+		stop()
+		return nil
+	}
+}
+
+// spawn starts the new instance. It is waited for in the background, so it is reaped and its failure reported if it
+// exits while this instance is still draining.
+func spawn(lns []net.Listener, cfg *Config) error {
+	files := make([]*os.File, 0, len(lns))
+	fds := make([]string, 0, len(lns))
+
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for i, ln := range lns {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return errors.New("listener does not expose its file descriptor")
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, f)
+		// ExtraFiles start at descriptor 3, after stdin, stdout and stderr.
+		fds = append(fds, strconv.Itoa(3+i))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(), _listenerFDEnv+"="+strings.Join(fds, ","))
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := cmd.Wait(); err != nil && cfg.ErrorLog != nil {
+			cfg.ErrorLog.Printf("httpkit: restarted process %d exited: %v", cmd.Process.Pid, err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+)
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+// RouteInfo describes the route a Router matched for a request.
+type RouteInfo struct {
+	Pattern string
+	Params  []Param
+	// Config holds the settings the route was registered with, nil if it has none.
+	Config *RouteConfig
+}
+
+func (ri *RouteInfo) Param(name string) string {
+	for _, p := range ri.Params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+var routeInfoKey = NewContextKey[*RouteInfo]("route info")
+
+func RouteFromContext(ctx context.Context) *RouteInfo {
+	return ContextValue(ctx, routeInfoKey)
+}
+
+// CaptureRoute prepares r so the route matched further down the chain can be read with RoutePattern once the handler
+// returned, even if middlewares in between replaced the request. Observability middlewares call it before next.
+func CaptureRoute(r *http.Request) *http.Request {
+	if RouteFromContext(r.Context()) != nil {
+		return r
+	}
+	return r.WithContext(ContextSet(r.Context(), routeInfoKey, &RouteInfo{}))
+}
+
+// RoutePattern returns the pattern matched by a Router or http.ServeMux, or "" if no route matched.
+func RoutePattern(r *http.Request) string {
+	if ri := RouteFromContext(r.Context()); ri != nil && ri.Pattern != "" {
+		return ri.Pattern
+	}
+	return r.Pattern
+}
+
+// PathParam returns the value of a path parameter matched by a Router or http.ServeMux.
+func PathParam(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
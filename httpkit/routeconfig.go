@@ -0,0 +1,81 @@
+package httpkit
+
+import (
+	"context"
+	"time"
+)
+
+// RouteConfig holds per-route settings given when registering a route on a Router. The router enforces Timeout and
+// MaxBodyBytes itself; the other settings are for middlewares, which read them with RouteConfigFromContext.
+type RouteConfig struct {
+	// Timeout bounds the request context of the handler.
+	Timeout time.Duration
+	// MaxBodyBytes limits the request body; DecodeJSON uses it as its default limit.
+	MaxBodyBytes int64
+	RateLimit    RateLimit
+	RequireAuth  bool
+	// Values carries application-specific settings.
+	Values map[string]any
+}
+
+// RateLimit allows Requests per Per. The zero value means no limit.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// RouteConfigFromContext returns the settings of the route matched by a Router, or nil if the route has none. As
+// the route is matched inside the router, it is only available to middlewares added with Use or Group and to
+// handlers.
+func RouteConfigFromContext(ctx context.Context) *RouteConfig {
+	if ri := RouteFromContext(ctx); ri != nil {
+		return ri.Config
+	}
+	return nil
+}
+
+func newRouteConfig(opts []RouteOption) *RouteConfig {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	var cfg RouteConfig
+	for _, opt := range opts {
+		opt.applyToRoute(&cfg)
+	}
+	return &cfg
+}
+
+type RouteOption interface {
+	applyToRoute(*RouteConfig)
+}
+
+type RouteOptionFunc func(*RouteConfig)
+
+func (f RouteOptionFunc) applyToRoute(c *RouteConfig) { f(c) }
+
+func WithRouteTimeout(d time.Duration) RouteOptionFunc {
+	return func(c *RouteConfig) { c.Timeout = d }
+}
+
+func WithRouteMaxBodyBytes(n int64) RouteOptionFunc {
+	return func(c *RouteConfig) { c.MaxBodyBytes = n }
+}
+
+func WithRouteRateLimit(requests int, per time.Duration) RouteOptionFunc {
+	return func(c *RouteConfig) { c.RateLimit = RateLimit{Requests: requests, Per: per} }
+}
+
+// WithRouteAuth marks the route as requiring an authenticated caller.
+func WithRouteAuth() RouteOptionFunc {
+	return func(c *RouteConfig) { c.RequireAuth = true }
+}
+
+func WithRouteValue(key string, value any) RouteOptionFunc {
+	return func(c *RouteConfig) {
+		if c.Values == nil {
+			c.Values = make(map[string]any)
+		}
+		c.Values[key] = value
+	}
+}
@@ -0,0 +1,363 @@
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type route struct {
+	pattern string
+	params  []string
+	handler http.Handler
+	cfg     *RouteConfig
+}
+
+type node struct {
+	static   map[string]*node
+	param    *node
+	wildcard *node
+	routes   map[string]*route
+}
+
+func (n *node) child(seg string) *node {
+	if n.static == nil {
+		n.static = make(map[string]*node)
+	}
+	c, ok := n.static[seg]
+	if !ok {
+		c = &node{}
+		n.static[seg] = c
+	}
+	return c
+}
+
+// Router dispatches requests by method and path. Patterns are made of "/" separated segments which are either
+// literal, a parameter such as {id} matching one segment, or a trailing wildcard such as {path...} matching the
+// rest of the path. Literal segments take precedence over parameters, which take precedence over wildcards.
+// Matched parameters are available through PathParam, r.PathValue and RouteFromContext.
+type Router struct {
+	root             node
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	options          http.Handler
+	mws              []Middleware
+}
+
+func NewRouter(opts ...RouterOption) *Router {
+	rt := Router{
+		notFound:         http.NotFoundHandler(),
+		methodNotAllowed: http.HandlerFunc(defaultMethodNotAllowed),
+		options:          http.HandlerFunc(defaultOptions),
+	}
+	for _, opt := range opts {
+		opt.applyToRouter(&rt)
+	}
+	return &rt
+}
+
+// Use appends middlewares wrapping the handlers of routes registered afterwards, including those of groups created
+// afterwards.
+func (rt *Router) Use(mws ...Middleware) {
+	rt.mws = append(rt.mws, mws...)
+}
+
+// Handle registers h for method and pattern, with per-route settings given by opts. It panics if the pattern is
+// invalid or already registered for method.
+func (rt *Router) Handle(method, pattern string, h http.Handler, opts ...RouteOption) {
+	if h == nil {
+		panic(fmt.Sprintf("httpkit: nil handler for %s %q", method, pattern))
+	}
+	rt.register(method, pattern, Chain(rt.mws...)(h), newRouteConfig(opts))
+}
+
+func (rt *Router) register(method, pattern string, h http.Handler, cfg *RouteConfig) {
+	if method == "" {
+		panic(fmt.Sprintf("httpkit: missing method for %q", pattern))
+	}
+
+	segments, err := splitPattern(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("httpkit: %s", err))
+	}
+
+	n := &rt.root
+	var params []string
+
+	for _, seg := range segments {
+		switch {
+		case seg.wildcard:
+			if n.wildcard == nil {
+				n.wildcard = &node{}
+			}
+			n = n.wildcard
+			params = append(params, seg.name)
+		case seg.param:
+			if n.param == nil {
+				n.param = &node{}
+			}
+			n = n.param
+			params = append(params, seg.name)
+		default:
+			n = n.child(seg.name)
+		}
+	}
+
+	if n.routes == nil {
+		n.routes = make(map[string]*route)
+	}
+
+	if existing, ok := n.routes[method]; ok {
+		panic(fmt.Sprintf("httpkit: %s %s conflicts with %s %s", method, pattern, method, existing.pattern))
+	}
+
+	n.routes[method] = &route{pattern: pattern, params: params, handler: h, cfg: cfg}
+}
+
+func (rt *Router) HandleFunc(method, pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(method, pattern, fn, opts...)
+}
+
+func (rt *Router) Get(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(http.MethodGet, pattern, fn, opts...)
+}
+
+func (rt *Router) Head(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(http.MethodHead, pattern, fn, opts...)
+}
+
+func (rt *Router) Post(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(http.MethodPost, pattern, fn, opts...)
+}
+
+func (rt *Router) Put(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(http.MethodPut, pattern, fn, opts...)
+}
+
+func (rt *Router) Patch(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(http.MethodPatch, pattern, fn, opts...)
+}
+
+func (rt *Router) Delete(pattern string, fn http.HandlerFunc, opts ...RouteOption) {
+	rt.Handle(http.MethodDelete, pattern, fn, opts...)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := splitPath(r.URL.EscapedPath())
+
+	var values []string
+	if n := match(&rt.root, segments, &values, r.Method); n != nil {
+		rte, ok := n.routes[r.Method]
+		if !ok {
+			rte = n.routes[http.MethodGet]
+		}
+		rt.serve(w, r, rte, values)
+		return
+	}
+
+	n := match(&rt.root, segments, &values, "")
+	if n == nil {
+		rt.notFound.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", n.allow())
+
+	if r.Method == http.MethodOptions {
+		rt.options.ServeHTTP(w, r)
+		return
+	}
+
+	rt.methodNotAllowed.ServeHTTP(w, r)
+}
+
+// allow lists the methods registered on n, including the implicit HEAD and OPTIONS.
+func (n *node) allow() string {
+	methods := []string{http.MethodOptions}
+	for m := range n.routes {
+		methods = append(methods, m)
+	}
+
+	if _, ok := n.routes[http.MethodGet]; ok {
+		if _, ok := n.routes[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+
+	slices.Sort(methods)
+	return strings.Join(slices.Compact(methods), ", ")
+}
+
+func defaultMethodNotAllowed(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+func defaultOptions(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rt *Router) serve(w http.ResponseWriter, r *http.Request, rte *route, values []string) {
+	ri := RouteFromContext(r.Context())
+	if ri == nil {
+		ri = &RouteInfo{}
+		r = r.WithContext(ContextSet(r.Context(), routeInfoKey, ri))
+	}
+
+	ri.Pattern = rte.pattern
+	ri.Params = ri.Params[:0]
+	ri.Config = rte.cfg
+	r.Pattern = rte.pattern
+
+	for i, name := range rte.params {
+		ri.Params = append(ri.Params, Param{Name: name, Value: values[i]})
+		r.SetPathValue(name, values[i])
+	}
+
+	if rte.cfg != nil {
+		if rte.cfg.MaxBodyBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, rte.cfg.MaxBodyBytes)
+		}
+
+		if rte.cfg.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), rte.cfg.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+	}
+
+	rte.handler.ServeHTTP(w, r)
+}
+
+// handles reports whether n has a route for method, any method if method is empty. HEAD falls back to GET.
+func (n *node) handles(method string) bool {
+	if n.routes == nil {
+		return false
+	}
+	if method == "" {
+		return true
+	}
+	if _, ok := n.routes[method]; ok {
+		return true
+	}
+	_, ok := n.routes[http.MethodGet]
+	return ok && method == http.MethodHead
+}
+
+// match walks the tree preferring literal segments, then parameters, then wildcards, backtracking on dead ends, and
+// returns the first node handling method.
+func match(n *node, segments []string, values *[]string, method string) *node {
+	if len(segments) == 0 {
+		if n.handles(method) {
+			return n
+		}
+		if n.wildcard != nil && n.wildcard.handles(method) {
+			*values = append(*values, "")
+			return n.wildcard
+		}
+		return nil
+	}
+
+	seg := segments[0]
+
+	if c, ok := n.static[seg]; ok {
+		if found := match(c, segments[1:], values, method); found != nil {
+			return found
+		}
+	}
+
+	if n.param != nil && seg != "" {
+		*values = append(*values, seg)
+		if found := match(n.param, segments[1:], values, method); found != nil {
+			return found
+		}
+		*values = (*values)[:len(*values)-1]
+	}
+
+	if n.wildcard != nil && n.wildcard.handles(method) {
+		*values = append(*values, strings.Join(segments, "/"))
+		return n.wildcard
+	}
+
+	return nil
+}
+
+type patternSegment struct {
+	name     string
+	param    bool
+	wildcard bool
+}
+
+func splitPattern(pattern string) ([]patternSegment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("pattern %q must start with /", pattern)
+	}
+
+	parts := strings.Split(pattern[1:], "/")
+	segments := make([]patternSegment, 0, len(parts))
+	seen := make(map[string]bool)
+
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("pattern %q: parameters must span a whole segment", pattern)
+			}
+			segments = append(segments, patternSegment{name: part})
+			continue
+		}
+
+		name := part[1 : len(part)-1]
+		seg := patternSegment{name: name, param: true}
+
+		if rest, ok := strings.CutSuffix(name, "..."); ok {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("pattern %q: wildcard must be the last segment", pattern)
+			}
+			seg = patternSegment{name: rest, wildcard: true}
+		}
+
+		if seg.name == "" || seen[seg.name] {
+			return nil, fmt.Errorf("pattern %q: empty or duplicate parameter name", pattern)
+		}
+		seen[seg.name] = true
+
+		segments = append(segments, seg)
+	}
+
+	return segments, nil
+}
+
+func splitPath(path string) []string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, seg := range segments {
+		if v, err := url.PathUnescape(seg); err == nil {
+			segments[i] = v
+		}
+	}
+	return segments
+}
+
+type RouterOption interface {
+	applyToRouter(*Router)
+}
+
+type RouterOptionFunc func(*Router)
+
+func (f RouterOptionFunc) applyToRouter(rt *Router) { f(rt) }
+
+func WithNotFound(h http.Handler) RouterOptionFunc {
+	return func(rt *Router) { rt.notFound = h }
+}
+
+// WithMethodNotAllowed replaces the 405 handler. The Allow header is already set when h is called.
+func WithMethodNotAllowed(h http.Handler) RouterOptionFunc {
+	return func(rt *Router) { rt.methodNotAllowed = h }
+}
+
+// WithOptions replaces the handler answering OPTIONS requests for paths without an explicit OPTIONS route, e.g. to
+// add CORS headers. The Allow header is already set when h is called.
+func WithOptions(h http.Handler) RouterOptionFunc {
+	return func(rt *Router) { rt.options = h }
+}
@@ -0,0 +1,335 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultCookieName  = "session"
+	_defaultIdleTimeout = 30 * time.Minute
+	_defaultLifetime    = 24 * time.Hour
+	_tokenLength        = 32
+)
+
+var ErrInvalidCookie = errors.New("invalid session cookie")
+
+type Manager struct {
+	store       Store
+	aeads       []cipher.AEAD
+	cookie      http.Cookie
+	idleTimeout time.Duration
+	lifetime    time.Duration
+	errorFunc   func(http.ResponseWriter, *http.Request, error)
+	now         func() time.Time
+	err         error
+}
+
+// NewManager creates a session manager. Without a Store, the whole session is kept in the cookie, encrypted and
+// authenticated with AES-GCM using the keys given to WithKeys.
+func NewManager(opts ...Option) (*Manager, error) {
+	m := Manager{
+		cookie: http.Cookie{
+			Name:     _defaultCookieName,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		idleTimeout: _defaultIdleTimeout,
+		lifetime:    _defaultLifetime,
+		errorFunc:   defaultErrorFunc,
+		now:         time.Now,
+	}
+
+	for _, opt := range opts {
+		opt.applyToManager(&m)
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.store == nil && len(m.aeads) == 0 {
+		return nil, errors.New("cookie sessions require at least one encryption key")
+	}
+
+	return &m, nil
+}
+
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.Load(r)
+		if err != nil {
+			m.errorFunc(w, r, err)
+			return
+		}
+
+		sw := &writer{ResponseWriter: w, m: m, r: r, s: s}
+		next.ServeHTTP(sw, r.WithContext(NewContext(r.Context(), s)))
+		sw.commit()
+	})
+}
+
+func (m *Manager) Load(r *http.Request) (*Session, error) {
+	now := m.now()
+
+	c, err := r.Cookie(m.cookie.Name)
+	if err != nil {
+		return newSession(now), nil
+	}
+
+	var (
+		rec   record
+		token string
+	)
+
+	if m.store == nil {
+		b, err := m.decrypt(c.Value)
+		if err != nil {
+			return newSession(now), nil
+		}
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return newSession(now), nil
+		}
+	} else {
+		b, found, err := m.store.Find(r.Context(), c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("finding session: %w", err)
+		}
+		if !found {
+			return newSession(now), nil
+		}
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, fmt.Errorf("decoding session: %w", err)
+		}
+		token = c.Value
+	}
+
+	if m.expired(rec, now) {
+		return newSession(now), nil
+	}
+
+	if rec.Values == nil {
+		rec.Values = make(map[string]json.RawMessage)
+	}
+
+	return &Session{token: token, created: rec.Created, values: rec.Values}, nil
+}
+
+func (m *Manager) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := r.Context()
+
+	if s.oldToken != "" && m.store != nil {
+		if err := m.store.Delete(ctx, s.oldToken); err != nil {
+			return fmt.Errorf("deleting renewed session: %w", err)
+		}
+		s.oldToken = ""
+	}
+
+	if s.status == destroyed {
+		if s.token != "" && m.store != nil {
+			if err := m.store.Delete(ctx, s.token); err != nil {
+				return fmt.Errorf("deleting session: %w", err)
+			}
+		}
+		m.clearCookie(w)
+		return nil
+	}
+
+	if len(s.values) == 0 && s.status == unmodified {
+		return nil
+	}
+
+	now := m.now()
+	expiry := m.expiry(s.created, now)
+
+	b, err := json.Marshal(record{Created: s.created, Seen: now, Values: s.values})
+	if err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+
+	var value string
+
+	if m.store == nil {
+		if value, err = m.encrypt(b); err != nil {
+			return fmt.Errorf("encrypting session: %w", err)
+		}
+	} else {
+		if s.token == "" {
+			if s.token, err = newToken(); err != nil {
+				return fmt.Errorf("generating session token: %w", err)
+			}
+		}
+		if err := m.store.Commit(ctx, s.token, b, expiry); err != nil {
+			return fmt.Errorf("committing session: %w", err)
+		}
+		value = s.token
+	}
+
+	c := m.cookie
+	c.Value = value
+	c.Expires = expiry
+	http.SetCookie(w, &c)
+
+	return nil
+}
+
+func (m *Manager) expired(rec record, now time.Time) bool {
+	return now.After(rec.Created.Add(m.lifetime)) || now.After(rec.Seen.Add(m.idleTimeout))
+}
+
+func (m *Manager) expiry(created, now time.Time) time.Time {
+	absolute, idle := created.Add(m.lifetime), now.Add(m.idleTimeout)
+	if absolute.Before(idle) {
+		return absolute
+	}
+	return idle
+}
+
+func (m *Manager) clearCookie(w http.ResponseWriter) {
+	c := m.cookie
+	c.Expires = time.Unix(1, 0)
+	c.MaxAge = -1
+	http.SetCookie(w, &c)
+}
+
+func (m *Manager) encrypt(b []byte) (string, error) {
+	aead := m.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, b, []byte(m.cookie.Name))), nil
+}
+
+func (m *Manager) decrypt(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+
+	for _, aead := range m.aeads {
+		if len(b) < aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := b[:aead.NonceSize()], b[aead.NonceSize():]
+		if plain, err := aead.Open(nil, nonce, ciphertext, []byte(m.cookie.Name)); err == nil {
+			return plain, nil
+		}
+	}
+
+	return nil, ErrInvalidCookie
+}
+
+func newToken() (string, error) {
+	b := make([]byte, _tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func defaultErrorFunc(w http.ResponseWriter, _ *http.Request, _ error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+type writer struct {
+	http.ResponseWriter
+	m      *Manager
+	r      *http.Request
+	s      *Session
+	once   sync.Once
+	failed bool
+}
+
+func (w *writer) commit() {
+	w.once.Do(func() {
+		if err := w.m.Save(w.ResponseWriter, w.r, w.s); err != nil {
+			w.failed = true
+			w.m.errorFunc(w.ResponseWriter, w.r, err)
+		}
+	})
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.commit()
+	if !w.failed {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.commit()
+	if w.failed {
+		return 0, errors.New("session: response aborted after commit failure")
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+type Option interface {
+	applyToManager(*Manager)
+}
+
+type OptionFunc func(*Manager)
+
+func (f OptionFunc) applyToManager(m *Manager) { f(m) }
+
+func WithStore(s Store) OptionFunc {
+	return func(m *Manager) { m.store = s }
+}
+
+// WithKeys sets the AES keys (16, 24 or 32 bytes) used to encrypt cookie sessions. The first key encrypts, every key
+// is tried when decrypting, so keys can be rotated by prepending a new one.
+func WithKeys(keys ...[]byte) OptionFunc {
+	return func(m *Manager) {
+		for _, key := range keys {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				m.err = fmt.Errorf("invalid session key: %w", err)
+				return
+			}
+			aead, err := cipher.NewGCM(block)
+			if err != nil {
+				m.err = fmt.Errorf("invalid session key: %w", err)
+				return
+			}
+			m.aeads = append(m.aeads, aead)
+		}
+	}
+}
+
+func WithCookie(c http.Cookie) OptionFunc {
+	return func(m *Manager) { m.cookie = c }
+}
+
+func WithCookieName(name string) OptionFunc {
+	return func(m *Manager) { m.cookie.Name = name }
+}
+
+func WithIdleTimeout(d time.Duration) OptionFunc {
+	return func(m *Manager) { m.idleTimeout = d }
+}
+
+func WithLifetime(d time.Duration) OptionFunc {
+	return func(m *Manager) { m.lifetime = d }
+}
+
+func WithErrorFunc(fn func(http.ResponseWriter, *http.Request, error)) OptionFunc {
+	return func(m *Manager) { m.errorFunc = fn }
+}
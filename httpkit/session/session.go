@@ -0,0 +1,145 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit"
+)
+
+type status int
+
+const (
+	unmodified status = iota
+	modified
+	destroyed
+)
+
+type Session struct {
+	mu       sync.Mutex
+	token    string
+	oldToken string
+	created  time.Time
+	values   map[string]json.RawMessage
+	status   status
+}
+
+type record struct {
+	Created time.Time                  `json:"created"`
+	Seen    time.Time                  `json:"seen"`
+	Values  map[string]json.RawMessage `json:"values"`
+}
+
+func newSession(now time.Time) *Session {
+	return &Session{created: now, values: make(map[string]json.RawMessage)}
+}
+
+var contextKey = httpkit.NewContextKey[*Session]("session")
+
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return httpkit.ContextSet(ctx, contextKey, s)
+}
+
+func FromContext(ctx context.Context) (*Session, bool) {
+	return httpkit.ContextGet(ctx, contextKey)
+}
+
+func Get[T any](s *Session, key string) (T, bool) {
+	var v T
+
+	s.mu.Lock()
+	raw, ok := s.values[key]
+	s.mu.Unlock()
+
+	if !ok {
+		return v, false
+	}
+
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return v, false
+	}
+
+	return v, true
+}
+
+func Set[T any](s *Session, key string, v T) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = raw
+	s.status = modified
+
+	return nil
+}
+
+// Pop returns the value stored under key and removes it from the session, which is handy for flash messages.
+func Pop[T any](s *Session, key string) (T, bool) {
+	v, ok := Get[T](s, key)
+	if ok {
+		s.Delete(key)
+	}
+	return v, ok
+}
+
+func (s *Session) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.values[key]
+	return ok
+}
+
+func (s *Session) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.status = modified
+	}
+}
+
+// Renew issues a new token for the session while keeping its data, which should be done on every privilege change
+// (e.g. login) to prevent session fixation.
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.oldToken == "" {
+		s.oldToken = s.token
+	}
+	s.token = ""
+	s.status = modified
+}
+
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values = make(map[string]json.RawMessage)
+	s.status = destroyed
+}
+
+func (s *Session) Created() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.created
+}
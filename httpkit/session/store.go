@@ -0,0 +1,81 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	// Find returns the data stored for token. found is false if the token does not exist or has expired.
+	Find(ctx context.Context, token string) (b []byte, found bool, err error)
+	Commit(ctx context.Context, token string, b []byte, expiry time.Time) error
+	Delete(ctx context.Context, token string) error
+}
+
+type memoryItem struct {
+	data   []byte
+	expiry time.Time
+}
+
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+func (s *MemoryStore) Find(_ context.Context, token string) ([]byte, bool, error) {
+	s.mu.RLock()
+	item, ok := s.items[token]
+	s.mu.RUnlock()
+
+	if !ok || time.Now().After(item.expiry) {
+		return nil, false, nil
+	}
+
+	return item.data, true, nil
+}
+
+func (s *MemoryStore) Commit(_ context.Context, token string, b []byte, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[token] = memoryItem{data: b, expiry: expiry}
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, token)
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for token, item := range s.items {
+		if now.After(item.expiry) {
+			delete(s.items, token)
+		}
+	}
+}
+
+// Cleanup removes expired sessions every interval until ctx is done.
+func (s *MemoryStore) Cleanup(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.DeleteExpired()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package httpkit
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+const (
+	_defaultShedInterval  = time.Second
+	_defaultShedIncrease  = 0.1
+	_defaultShedDecrease  = 0.5
+	_defaultShedMaxDrop   = 0.9
+	_defaultShedMinSample = 20
+	_maxShedSamples       = 4096
+)
+
+// LoadShedder rejects a growing fraction of requests while the server is overloaded, which it detects by the p99
+// latency of the last interval or the number of in-flight requests crossing their targets. Like TCP congestion
+// control it adjusts additively and multiplicatively (AIMD): each overloaded interval raises the drop fraction by a
+// fixed step, each healthy one cuts it by a factor, so shedding stops quickly once the overload is over. Priority
+// requests, by default /livez and /readyz, are never shed.
+type LoadShedder struct {
+	targetLatency time.Duration
+	maxInFlight   int64
+	interval      time.Duration
+	increase      float64
+	decrease      float64
+	maxDrop       float64
+	priority      func(*http.Request) bool
+
+	inFlight atomic.Int64
+
+	mu        sync.Mutex
+	drop      float64
+	samples   []time.Duration
+	evaluated time.Time
+}
+
+// NewLoadShedder creates a shedder with the given overload thresholds. A zero threshold is not checked.
+func NewLoadShedder(targetLatency time.Duration, maxInFlight int, opts ...ShedderOption) *LoadShedder {
+	s := LoadShedder{
+		targetLatency: targetLatency,
+		maxInFlight:   int64(maxInFlight),
+		interval:      _defaultShedInterval,
+		increase:      _defaultShedIncrease,
+		decrease:      _defaultShedDecrease,
+		maxDrop:       _defaultShedMaxDrop,
+		priority:      isHealthCheck,
+		evaluated:     time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt.applyToShedder(&s)
+	}
+
+	return &s
+}
+
+// DropFraction returns the fraction of non-priority requests currently rejected.
+func (s *LoadShedder) DropFraction() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.drop
+}
+
+func (s *LoadShedder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.priority(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.shed() {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(s.interval.Seconds()), 1)))
+			_ = problem.Write(w, problem.New(http.StatusServiceUnavailable).
+				WithDetail("server is overloaded").
+				WithInstance(r.URL.Path))
+			return
+		}
+
+		s.inFlight.Add(1)
+		start := time.Now()
+		defer func() {
+			s.inFlight.Add(-1)
+			s.observe(time.Since(start))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *LoadShedder) shed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evaluate()
+
+	return s.drop > 0 && rand.Float64() < s.drop
+}
+
+func (s *LoadShedder) observe(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) < _maxShedSamples {
+		s.samples = append(s.samples, d)
+	}
+}
+
+// evaluate adjusts the drop fraction once per interval. It must be called with s.mu held.
+func (s *LoadShedder) evaluate() {
+	if time.Since(s.evaluated) < s.interval {
+		return
+	}
+
+	if s.overloaded() {
+		s.drop = min(s.drop+s.increase, s.maxDrop)
+	} else if s.drop *= s.decrease; s.drop < 0.01 {
+		s.drop = 0
+	}
+
+	s.samples = s.samples[:0]
+	s.evaluated = time.Now()
+}
+
+func (s *LoadShedder) overloaded() bool {
+	if s.maxInFlight > 0 && s.inFlight.Load() >= s.maxInFlight {
+		return true
+	}
+
+	if s.targetLatency <= 0 || len(s.samples) < _defaultShedMinSample {
+		return false
+	}
+
+	slices.Sort(s.samples)
+	return s.samples[len(s.samples)*99/100] > s.targetLatency
+}
+
+func isHealthCheck(r *http.Request) bool {
+	return r.URL.Path == "/livez" || r.URL.Path == "/readyz"
+}
+
+type ShedderOption interface {
+	applyToShedder(*LoadShedder)
+}
+
+type ShedderOptionFunc func(*LoadShedder)
+
+func (f ShedderOptionFunc) applyToShedder(s *LoadShedder) { f(s) }
+
+// WithShedInterval sets how often the drop fraction is adjusted. The default is 1 second.
+func WithShedInterval(d time.Duration) ShedderOptionFunc {
+	return func(s *LoadShedder) { s.interval = d }
+}
+
+// WithShedAIMD sets the step added to the drop fraction per overloaded interval, 0.1 by default, and the factor it
+// is multiplied with per healthy interval, 0.5 by default.
+func WithShedAIMD(increase, decrease float64) ShedderOptionFunc {
+	return func(s *LoadShedder) { s.increase, s.decrease = increase, decrease }
+}
+
+// WithShedMaxDrop caps the drop fraction so some requests always get through to measure recovery. The default is
+// 0.9.
+func WithShedMaxDrop(f float64) ShedderOptionFunc {
+	return func(s *LoadShedder) { s.maxDrop = f }
+}
+
+// WithShedPriority replaces the default priority check for health endpoints. Requests for which fn returns true are
+// never shed and not measured.
+func WithShedPriority(fn func(*http.Request) bool) ShedderOptionFunc {
+	return func(s *LoadShedder) { s.priority = fn }
+}
@@ -0,0 +1,125 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+const _defaultBufferSize = 16
+
+// Broadcaster fans events out to all subscribers. Publishing never blocks: a subscriber whose buffer is full is
+// dropped, and its client reconnects with Last-Event-ID.
+type Broadcaster struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	buffer int
+	opts   []Option
+	closed bool
+}
+
+type BroadcasterOption interface {
+	applyToBroadcaster(*Broadcaster)
+}
+
+type BroadcasterOptionFunc func(*Broadcaster)
+
+func (f BroadcasterOptionFunc) applyToBroadcaster(b *Broadcaster) { f(b) }
+
+func NewBroadcaster(opts ...BroadcasterOption) *Broadcaster {
+	b := Broadcaster{subs: make(map[chan Event]struct{}), buffer: _defaultBufferSize}
+	for _, opt := range opts {
+		opt.applyToBroadcaster(&b)
+	}
+	return &b
+}
+
+// Subscribe returns a channel receiving published events until ctx is done, the subscriber falls behind or the
+// broadcaster is closed, at which point the channel is closed.
+func (b *Broadcaster) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, b.buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch
+	}
+
+	b.subs[ch] = struct{}{}
+
+	context.AfterFunc(ctx, func() { b.unsubscribe(ch) })
+
+	return ch
+}
+
+func (b *Broadcaster) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *Broadcaster) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close disconnects all subscribers, e.g. on server shutdown.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// ServeHTTP streams published events to the client until it disconnects or the stream fails, e.g. on a failed
+// heartbeat.
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sw, err := NewWriter(w, r, b.opts...)
+	if err != nil {
+		return
+	}
+	defer sw.Close()
+
+	// The subscription ends with the writer, not only with the request, so a stream that failed does not keep
+	// receiving events until the client is gone.
+	for e := range b.Subscribe(sw.ctx) {
+		if err := sw.Send(e); err != nil {
+			return
+		}
+	}
+}
+
+// WithBufferSize sets how many events a subscriber may fall behind before it is dropped.
+func WithBufferSize(n int) BroadcasterOptionFunc {
+	return func(b *Broadcaster) { b.buffer = n }
+}
+
+// WithWriterOptions sets the options of the writers created by ServeHTTP.
+func WithWriterOptions(opts ...Option) BroadcasterOptionFunc {
+	return func(b *Broadcaster) { b.opts = append(b.opts, opts...) }
+}
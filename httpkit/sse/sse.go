@@ -0,0 +1,216 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const _defaultHeartbeat = 15 * time.Second
+
+var ErrClosed = errors.New("sse: stream closed")
+
+// Event is a single server-sent event. Empty fields are omitted; a Data with newlines is sent as several data lines.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// Writer streams events to one client. It is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	ctx    context.Context
+	cancel context.CancelFunc
+	buf    bytes.Buffer
+	// done is closed when the heartbeat goroutine has returned.
+	done chan struct{}
+}
+
+type config struct {
+	heartbeat time.Duration
+	retry     time.Duration
+}
+
+// NewWriter starts an event stream on w. It clears the server write deadline, since streams outlive WriteTimeout,
+// and sends a comment as heartbeat at the configured interval so proxies keep the connection open. The stream ends
+// when the client disconnects or Close is called, whichever comes first; Done reports it.
+func NewWriter(w http.ResponseWriter, r *http.Request, opts ...Option) (*Writer, error) {
+	cfg := config{heartbeat: _defaultHeartbeat}
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	sw := Writer{w: w, rc: rc, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+
+	if cfg.retry > 0 {
+		fmt.Fprintf(&sw.buf, "retry: %d\n\n", cfg.retry.Milliseconds())
+	}
+
+	sw.mu.Lock()
+	err := sw.flush()
+	sw.mu.Unlock()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if cfg.heartbeat > 0 {
+		go sw.heartbeat(cfg.heartbeat)
+	} else {
+		close(sw.done)
+	}
+
+	return &sw, nil
+}
+
+// LastEventID returns the ID of the last event a reconnecting client received.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+func (w *Writer) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Event, "\r\n") {
+		return fmt.Errorf("sse: event id and name must not contain newlines")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	if e.ID != "" {
+		w.buf.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		w.buf.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		w.buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\n") {
+		w.buf.WriteString("data: " + line + "\n")
+	}
+	w.buf.WriteByte('\n')
+
+	return w.flush()
+}
+
+// SendJSON sends v encoded as JSON in an event named event.
+func (w *Writer) SendJSON(event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	return w.Send(Event{Event: event, Data: string(b)})
+}
+
+// Comment sends a comment line, which clients ignore.
+func (w *Writer) Comment(text string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		w.buf.WriteString(": " + line + "\n")
+	}
+	w.buf.WriteByte('\n')
+
+	return w.flush()
+}
+
+// Done is closed when the client disconnects or the writer is closed.
+func (w *Writer) Done() <-chan struct{} {
+	return w.ctx.Done()
+}
+
+// Close stops the heartbeat and makes further sends fail, waiting for a send in progress and the heartbeat to finish,
+// so the ResponseWriter is no longer used once it returns. The handler should return afterwards.
+func (w *Writer) Close() {
+	w.mu.Lock()
+	w.cancel()
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+// flush writes the buffered event to the client. The caller must hold w.mu.
+func (w *Writer) flush() error {
+	defer w.buf.Reset()
+
+	if _, err := w.w.Write(w.buf.Bytes()); err != nil {
+		w.cancel()
+		return err
+	}
+
+	if err := w.rc.Flush(); err != nil {
+		w.cancel()
+		return err
+	}
+
+	return nil
+}
+
+func (w *Writer) heartbeat(interval time.Duration) {
+	defer close(w.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-t.C:
+			if err := w.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+type Option interface {
+	applyToConfig(*config)
+}
+
+type OptionFunc func(*config)
+
+func (f OptionFunc) applyToConfig(c *config) { f(c) }
+
+// WithHeartbeat sets the heartbeat interval. Zero disables heartbeats.
+func WithHeartbeat(interval time.Duration) OptionFunc {
+	return func(c *config) { c.heartbeat = interval }
+}
+
+// WithRetry tells clients how long to wait before reconnecting.
+func WithRetry(d time.Duration) OptionFunc {
+	return func(c *config) { c.retry = d }
+}
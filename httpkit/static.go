@@ -0,0 +1,243 @@
+package httpkit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	_immutableCacheControl = "public, max-age=31536000, immutable"
+	_minHashLen            = 8
+	_defaultCacheControl   = "no-cache"
+)
+
+// precompressed lists the encodings looked up next to a file, in order of preference.
+var precompressed = []struct{ encoding, ext string }{
+	{encoding: "br", ext: ".br"},
+	{encoding: "gzip", ext: ".gz"},
+}
+
+type staticConfig struct {
+	index        string
+	spa          bool
+	listing      bool
+	cacheControl string
+	immutable    func(name string) bool
+}
+
+type staticHandler struct {
+	fsys fs.FS
+	cfg  staticConfig
+}
+
+// Static serves the files of fsys. Files with a content hash in their name, such as app.3f9a1c2e.js, are served as
+// immutable, everything else must be revalidated. A precompressed name.br or name.gz next to a file is served instead
+// when the client accepts it. Directory listings are disabled unless WithDirectoryListing is given. Mount it with
+// http.StripPrefix when it is not served from the root.
+func Static(fsys fs.FS, opts ...StaticOption) http.Handler {
+	cfg := staticConfig{
+		index:        "index.html",
+		cacheControl: _defaultCacheControl,
+		immutable:    isHashedName,
+	}
+
+	for _, opt := range opts {
+		opt.applyToStatic(&cfg)
+	}
+
+	return &staticHandler{fsys: fsys, cfg: cfg}
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+
+	fi, err := fs.Stat(h.fsys, name)
+	if err == nil && fi.IsDir() {
+		index := path.Join(name, h.cfg.index)
+		if _, err := fs.Stat(h.fsys, index); err == nil {
+			h.serveFile(w, r, index)
+			return
+		}
+
+		if h.cfg.listing {
+			http.FileServerFS(h.fsys).ServeHTTP(w, r)
+			return
+		}
+
+		err = fs.ErrNotExist
+	}
+
+	if err != nil {
+		// Only paths that look like client-side routes fall back to the index, so missing assets still 404.
+		if h.cfg.spa && errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+			h.serveFile(w, r, h.cfg.index)
+			return
+		}
+
+		serveFSError(w, err)
+		return
+	}
+
+	h.serveFile(w, r, name)
+}
+
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if h.cfg.immutable(name) {
+		w.Header().Set("Cache-Control", _immutableCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", h.cfg.cacheControl)
+	}
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+
+	served := name
+	for _, pc := range precompressed {
+		if !acceptsEncoding(r, pc.encoding) {
+			continue
+		}
+		if fi, err := fs.Stat(h.fsys, name+pc.ext); err == nil && !fi.IsDir() {
+			served = name + pc.ext
+			w.Header().Set("Content-Encoding", pc.encoding)
+			if ctype == "" {
+				ctype = "application/octet-stream"
+			}
+			break
+		}
+	}
+
+	if ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	f, err := h.fsys.Open(served)
+	if err != nil {
+		w.Header().Del("Content-Encoding")
+		serveFSError(w, err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		serveFSError(w, err)
+		return
+	}
+
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			serveFSError(w, err)
+			return
+		}
+		rs = bytes.NewReader(b)
+	}
+
+	http.ServeContent(w, r, name, fi.ModTime(), rs)
+}
+
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, ar := range parseAccept(r.Header.Get("Accept-Encoding")) {
+		if ar.value == encoding {
+			return ar.q > 0
+		}
+	}
+	return false
+}
+
+func serveFSError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// isHashedName reports whether the last dot- or dash-separated part of the file name before its extension looks
+// like a content hash as bundlers such as webpack and Vite generate them: at least 8 hex digits, or at least 8
+// base64url characters, including a decimal digit and, unless hex, both upper and lower case letters. Words with
+// digits, such as "android192", are not hashes. A rare hash lacking one of the classes is only revalidated; use
+// WithImmutable for other naming schemes.
+func isHashedName(name string) bool {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+
+	hash := base[strings.LastIndexAny(base, ".-")+1:]
+	if len(hash) < _minHashLen || len(hash) == len(base) {
+		return false
+	}
+
+	var digit, lower, upper, nonHex bool
+	for _, c := range hash {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'f':
+			lower = true
+		case c >= 'a' && c <= 'z':
+			lower, nonHex = true, true
+		case c >= 'A' && c <= 'F':
+			upper = true
+		case c >= 'A' && c <= 'Z':
+			upper, nonHex = true, true
+		case c == '_':
+			nonHex = true
+		default:
+			return false
+		}
+	}
+
+	return digit && (!nonHex || lower && upper)
+}
+
+type StaticOption interface {
+	applyToStatic(*staticConfig)
+}
+
+type StaticOptionFunc func(*staticConfig)
+
+func (f StaticOptionFunc) applyToStatic(c *staticConfig) { f(c) }
+
+// WithSPAFallback serves the index file for missing paths without an extension so client-side routing works.
+func WithSPAFallback() StaticOptionFunc {
+	return func(c *staticConfig) { c.spa = true }
+}
+
+// WithIndex sets the file served for directories and by WithSPAFallback. The default is index.html.
+func WithIndex(name string) StaticOptionFunc {
+	return func(c *staticConfig) { c.index = name }
+}
+
+func WithDirectoryListing() StaticOptionFunc {
+	return func(c *staticConfig) { c.listing = true }
+}
+
+// WithStaticCacheControl sets the Cache-Control header of files that are not immutable. The default is no-cache.
+func WithStaticCacheControl(value string) StaticOptionFunc {
+	return func(c *staticConfig) { c.cacheControl = value }
+}
+
+// WithImmutable replaces how files that never change under the same name are recognized.
+func WithImmutable(fn func(name string) bool) StaticOptionFunc {
+	return func(c *staticConfig) { c.immutable = fn }
+}
@@ -0,0 +1,32 @@
+package httpkit
+
+import "testing"
+
+func TestIsHashedName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "app.3f9a1c2e.js", want: true},
+		{name: "assets/app-3F9A1C2E.css", want: true},
+		{name: "main.5d41402abc4b2a76b9719d911017c592.js", want: true},
+		{name: "index.Bx7kQ2zP.js", want: true},
+		{name: "chunk-a1_Bc2De.js", want: true},
+		{name: "icon-android192.png", want: false},
+		{name: "logo-version2x.svg", want: false},
+		{name: "app.deadbeef.js", want: false},
+		{name: "app.3f9a1c2.js", want: false},
+		{name: "jquery-3.7.1.min.js", want: false},
+		{name: "3f9a1c2e.js", want: false},
+		{name: "index.html", want: false},
+		{name: "app.3f9a1c2e", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHashedName(tt.name); got != tt.want {
+				t.Errorf("isHashedName(%q) = %t, want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package httpkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+const _copyBufferSize = 32 << 10
+
+// FlushWriter flushes the response after every write so clients receive data as soon as it is written.
+type FlushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func NewFlushWriter(w http.ResponseWriter) *FlushWriter {
+	return &FlushWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (f *FlushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return n, err
+	}
+
+	return n, nil
+}
+
+func (f *FlushWriter) Flush() error {
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// DisableWriteTimeout clears the server write deadline for this response, which long-running streams would
+// otherwise exceed.
+func (f *FlushWriter) DisableWriteTimeout() error {
+	if err := f.rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// NDJSONEncoder streams values as newline-delimited JSON, flushing after each one.
+type NDJSONEncoder struct {
+	fw  *FlushWriter
+	enc *json.Encoder
+}
+
+// NewNDJSONEncoder sets the application/x-ndjson content type on w. The status code is sent with the first value.
+func NewNDJSONEncoder(w http.ResponseWriter) *NDJSONEncoder {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	fw := NewFlushWriter(w)
+	return &NDJSONEncoder{fw: fw, enc: json.NewEncoder(fw)}
+}
+
+func (e *NDJSONEncoder) Encode(v any) error {
+	return e.enc.Encode(v)
+}
+
+// CopyContext copies src to dst like io.Copy but stops with the context error once ctx is done, e.g. because the
+// client disconnected. If dst is an http.ResponseWriter or FlushWriter, it is flushed after every chunk.
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	var flush func() error
+	switch w := dst.(type) {
+	case *FlushWriter:
+		flush = w.Flush
+	case http.ResponseWriter:
+		flush = NewFlushWriter(w).Flush
+	}
+
+	buf := make([]byte, _copyBufferSize)
+
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			m, werr := dst.Write(buf[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+			if flush != nil {
+				if err := flush(); err != nil {
+					return written, err
+				}
+			}
+		}
+
+		if errors.Is(rerr, io.EOF) {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
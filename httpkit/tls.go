@@ -0,0 +1,163 @@
+package httpkit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// TLSError reports which part of the TLS configuration could not be loaded.
+type TLSError struct {
+	// What names the part, e.g. "CA bundle", "certificate" or "key pair".
+	What string
+	// File is the offending file, empty when the PEM bytes were given directly.
+	File string
+	Err  error
+}
+
+func (e *TLSError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("loading TLS %s: %v", e.What, e.Err)
+	}
+	return fmt.Sprintf("loading TLS %s from %s: %v", e.What, e.File, e.Err)
+}
+
+func (e *TLSError) Unwrap() error { return e.Err }
+
+// WithTLS serves HTTPS with mutual TLS, verifying clients against the CA bundle in caFile. Without a caFile clients
+// are not asked for certificates, which is plain HTTPS. The files are read when the configuration is validated, so
+// errors surface from Validate or Serve as a *TLSError.
+func WithTLS(caFile, ceFile, keyFile string) ConfigOption {
+	return tlsFromFiles(os.ReadFile, caFile, ceFile, keyFile)
+}
+
+// WithTLSFromFS is WithTLS reading from fsys, e.g. an embed.FS, so sealed binaries carry their certificates.
+func WithTLSFromFS(fsys fs.FS, caFile, ceFile, keyFile string) ConfigOption {
+	return tlsFromFiles(func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) }, caFile, ceFile, keyFile)
+}
+
+// WithTLSFromPEM is WithTLS taking PEM encoded bytes, for certificates held in memory or injected by tests.
+func WithTLSFromPEM(caPEM, certPEM, keyPEM []byte) ConfigOption {
+	return tlsOption{value: func() (*tls.Config, error) {
+		return newTLSConfig(caPEM, certPEM, keyPEM, "", "", "")
+	}}
+}
+
+// WithTLSConfig loads the TLS configuration through fn during validation, e.g. to fetch certificates from a secret
+// store.
+func WithTLSConfig(fn func() (*tls.Config, error)) ConfigOption {
+	return tlsOption{value: fn}
+}
+
+func tlsFromFiles(readFile func(string) ([]byte, error), caFile, ceFile, keyFile string) ConfigOption {
+	return tlsOption{value: func() (*tls.Config, error) {
+		var ca []byte
+		if caFile != "" {
+			var err error
+			if ca, err = readFile(caFile); err != nil {
+				return nil, &TLSError{What: "CA bundle", File: caFile, Err: err}
+			}
+		}
+
+		ce, err := readFile(ceFile)
+		if err != nil {
+			return nil, &TLSError{What: "certificate", File: ceFile, Err: err}
+		}
+
+		key, err := readFile(keyFile)
+		if err != nil {
+			return nil, &TLSError{What: "key", File: keyFile, Err: err}
+		}
+
+		return newTLSConfig(ca, ce, key, caFile, ceFile, keyFile)
+	}}
+}
+
+func newTLSConfig(caPEM, certPEM, keyPEM []byte, caFile, ceFile, keyFile string) (*tls.Config, error) {
+	ce, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		file := ceFile
+		if keyFile != "" {
+			file += " and " + keyFile
+		}
+		return nil, &TLSError{What: "key pair", File: file, Err: err}
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{ce},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if len(caPEM) > 0 {
+		pool := x509.NewCertPool()
+		if ok := pool.AppendCertsFromPEM(caPEM); !ok {
+			return nil, &TLSError{What: "CA bundle", File: caFile, Err: errors.New("no certificates found in PEM")}
+		}
+		cfg.ClientCAs, cfg.ClientAuth = pool, tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// tlsPolicy holds the settings of WithTLSClientAuth, WithTLSMinVersion and WithTLSCipherSuites, applied to whichever
+// TLS configuration is loaded or set in Config.TLS, regardless of the option order.
+type tlsPolicy struct {
+	clientAuth   *tls.ClientAuthType
+	minVersion   uint16
+	cipherSuites []uint16
+}
+
+func (p tlsPolicy) apply(cfg *tls.Config) error {
+	if p.clientAuth != nil {
+		cfg.ClientAuth = *p.clientAuth
+	}
+
+	if cfg.ClientAuth >= tls.VerifyClientCertIfGiven && cfg.ClientCAs == nil {
+		return fmt.Errorf("client auth %s requires a CA bundle", cfg.ClientAuth)
+	}
+
+	if p.minVersion != 0 {
+		if p.minVersion < tls.VersionTLS12 {
+			return fmt.Errorf("minimum version %s is insecure", tls.VersionName(p.minVersion))
+		}
+		cfg.MinVersion = p.minVersion
+	}
+
+	if len(p.cipherSuites) > 0 {
+		insecure := tls.InsecureCipherSuites()
+		for _, id := range p.cipherSuites {
+			for _, cs := range insecure {
+				if cs.ID == id {
+					return fmt.Errorf("cipher suite %s is insecure", cs.Name)
+				}
+			}
+		}
+		cfg.CipherSuites = p.cipherSuites
+	}
+
+	return nil
+}
+
+// WithTLSClientAuth sets whether clients must present a certificate. WithTLS requires and verifies one when given a
+// CA bundle and asks for none otherwise.
+func WithTLSClientAuth(v tls.ClientAuthType) ConfigOption { return tlsClientAuthOption{value: v} }
+
+// WithTLSMinVersion sets the minimum TLS version, which defaults to and may not be lower than TLS 1.2.
+func WithTLSMinVersion(v uint16) ConfigOption { return tlsMinVersionOption{value: v} }
+
+// WithTLSCipherSuites restricts the cipher suites for TLS 1.2 and below. TLS 1.3 suites are not configurable.
+func WithTLSCipherSuites(v ...uint16) ConfigOption { return tlsCipherSuitesOption{value: v} }
+
+type (
+	tlsClientAuthOption   struct{ value tls.ClientAuthType }
+	tlsMinVersionOption   struct{ value uint16 }
+	tlsCipherSuitesOption struct{ value []uint16 }
+)
+
+func (o tlsClientAuthOption) applyToConfig(cfg *Config)   { cfg.tlsPolicy.clientAuth = &o.value }
+func (o tlsMinVersionOption) applyToConfig(cfg *Config)   { cfg.tlsPolicy.minVersion = o.value }
+func (o tlsCipherSuitesOption) applyToConfig(cfg *Config) { cfg.tlsPolicy.cipherSuites = o.value }
@@ -0,0 +1,139 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/drakelthedragon/toolbox/httpkit"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const _instrumentationName = "github.com/drakelthedragon/toolbox/httpkit/tracing"
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	route      func(*http.Request) string
+	filter     func(*http.Request) bool
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		route:      httpkit.RoutePattern,
+	}
+
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	return cfg
+}
+
+// Middleware starts a server span for every request, continuing the trace from an incoming W3C traceparent header.
+// The span is stored in the request context, so spans started further down, e.g. by pgxkit's query tracer, become
+// its children. Once the handler returns the span is renamed after the matched route and annotated with the status.
+func Middleware(opts ...Option) httpkit.Middleware {
+	cfg := newConfig(opts)
+	tracer := cfg.provider.Tracer(_instrumentationName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.filter != nil && !cfg.filter(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := cfg.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(requestAttributes(r)...),
+			)
+			defer span.End()
+
+			r = httpkit.CaptureRoute(r.WithContext(ctx))
+			rw := httpkit.WrapResponseWriter(w)
+
+			defer func() {
+				if rec := recover(); rec != nil {
+					span.RecordError(fmt.Errorf("panic: %v", rec))
+					span.SetStatus(codes.Error, "panic")
+					panic(rec)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if route := cfg.route(r); route != "" {
+				span.SetName(spanName(r.Method, route))
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			status := rw.Status()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+func requestAttributes(r *http.Request) []attribute.KeyValue {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(scheme),
+		semconv.ServerAddress(r.Host),
+		semconv.NetworkProtocolVersion(fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+	}
+
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+
+	return attrs
+}
+
+// spanName strips the method from Go 1.22 style patterns such as "GET /users/{id}" before prefixing it.
+func spanName(method, route string) string {
+	if len(route) > len(method) && route[:len(method)] == method && route[len(method)] == ' ' {
+		route = route[len(method)+1:]
+	}
+	return method + " " + route
+}
+
+type Option interface {
+	applyToConfig(*config)
+}
+
+type OptionFunc func(*config)
+
+func (f OptionFunc) applyToConfig(c *config) { f(c) }
+
+func WithTracerProvider(tp trace.TracerProvider) OptionFunc {
+	return func(c *config) { c.provider = tp }
+}
+
+func WithPropagator(p propagation.TextMapPropagator) OptionFunc {
+	return func(c *config) { c.propagator = p }
+}
+
+func WithRouteFunc(fn func(*http.Request) string) OptionFunc {
+	return func(c *config) { c.route = fn }
+}
+
+// WithFilter skips tracing for requests for which fn returns false, e.g. health checks.
+func WithFilter(fn func(*http.Request) bool) OptionFunc {
+	return func(c *config) { c.filter = fn }
+}
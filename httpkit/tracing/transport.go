@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/drakelthedragon/toolbox/httpkit/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport starts a client span for every outbound request and injects its traceparent header, so the server's
+// Middleware continues the same trace. The filter option is called with the outbound request; the route option does
+// not apply.
+func Transport(opts ...Option) client.Middleware {
+	cfg := newConfig(opts)
+	tracer := cfg.provider.Tracer(_instrumentationName)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if cfg.filter != nil && !cfg.filter(req) {
+				return next.RoundTrip(req)
+			}
+
+			ctx, span := tracer.Start(req.Context(), req.Method,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(clientAttributes(req)...),
+			)
+			defer span.End()
+
+			req = req.Clone(ctx)
+			cfg.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+
+			return resp, nil
+		})
+	}
+}
+
+func clientAttributes(r *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLFull(r.URL.Redacted()),
+		semconv.ServerAddress(r.URL.Hostname()),
+	}
+
+	if port, err := strconv.Atoi(r.URL.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+
+	return attrs
+}
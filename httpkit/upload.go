@@ -0,0 +1,304 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	_defaultUploadMaxBytes     = 32 << 20
+	_defaultUploadMaxFileBytes = 10 << 20
+	_defaultUploadMaxFiles     = 10
+	_defaultUploadMaxValue     = 1 << 20
+	_sniffLen                  = 512
+)
+
+type UploadedFile struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Size        int64
+	// Location is where the Destination stored the file, e.g. its path on disk or its blob key.
+	Location string
+}
+
+type MultipartForm struct {
+	Values url.Values
+	Files  []UploadedFile
+}
+
+// Destination receives uploaded files as they are streamed from the request.
+type Destination interface {
+	Save(ctx context.Context, f *UploadedFile, r io.Reader) (location string, err error)
+	// Remove deletes a saved file when a later part of the upload fails.
+	Remove(ctx context.Context, location string) error
+}
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+}
+
+type uploadConfig struct {
+	maxBytes     int64
+	maxFileBytes int64
+	maxFiles     int
+	allowedTypes []string
+}
+
+// ParseUpload streams a multipart/form-data request into dst without buffering files in memory or temporary
+// files. The content type of each file is sniffed from its first bytes rather than trusted from the client. If any
+// part fails, files already saved are removed. Errors are returned as an *Error with a suitable status code.
+func ParseUpload(r *http.Request, dst Destination, opts ...UploadOption) (*MultipartForm, error) {
+	cfg := uploadConfig{
+		maxBytes:     _defaultUploadMaxBytes,
+		maxFileBytes: _defaultUploadMaxFileBytes,
+		maxFiles:     _defaultUploadMaxFiles,
+	}
+
+	for _, opt := range opts {
+		opt.applyToUpload(&cfg)
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, cfg.maxBytes)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, &Error{Status: http.StatusUnsupportedMediaType, Message: "request must be multipart/form-data", Err: err}
+	}
+
+	ctx := r.Context()
+	form := MultipartForm{Values: make(url.Values)}
+
+	cleanup := func() {
+		for _, f := range form.Files {
+			_ = dst.Remove(context.WithoutCancel(ctx), f.Location)
+		}
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return &form, nil
+		}
+		if err != nil {
+			cleanup()
+			return nil, uploadError(err)
+		}
+
+		if part.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(part, _defaultUploadMaxValue+1))
+			if err == nil && len(b) > _defaultUploadMaxValue {
+				err = Errorf(http.StatusRequestEntityTooLarge, "form field %q is too large", part.FormName())
+			}
+			if err != nil {
+				cleanup()
+				return nil, uploadError(err)
+			}
+			form.Values.Add(part.FormName(), string(b))
+			continue
+		}
+
+		if len(form.Files) == cfg.maxFiles {
+			cleanup()
+			return nil, Errorf(http.StatusRequestEntityTooLarge, "request must not contain more than %d files", cfg.maxFiles)
+		}
+
+		f, err := saveUpload(ctx, dst, &cfg, part.FormName(), part.FileName(), part)
+		if err != nil {
+			cleanup()
+			return nil, uploadError(err)
+		}
+
+		form.Files = append(form.Files, *f)
+	}
+}
+
+func saveUpload(ctx context.Context, dst Destination, cfg *uploadConfig, field, filename string, part io.Reader) (*UploadedFile, error) {
+	head := make([]byte, _sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	f := &UploadedFile{
+		Field:       field,
+		Filename:    filepath.Base(filename),
+		ContentType: http.DetectContentType(head),
+	}
+
+	if !typeAllowed(f.ContentType, cfg.allowedTypes) {
+		return nil, Errorf(http.StatusUnsupportedMediaType, "file %q has unsupported content type %s", f.Filename, f.ContentType)
+	}
+
+	cr := &countingReader{r: io.MultiReader(bytes.NewReader(head), io.LimitReader(part, cfg.maxFileBytes-int64(n)+1))}
+
+	location, err := dst.Save(ctx, f, cr)
+	if err != nil {
+		return nil, fmt.Errorf("saving %q: %w", f.Filename, err)
+	}
+
+	if cr.n > cfg.maxFileBytes {
+		_ = dst.Remove(context.WithoutCancel(ctx), location)
+		return nil, Errorf(http.StatusRequestEntityTooLarge, "file %q must not be larger than %d bytes", f.Filename, cfg.maxFileBytes)
+	}
+
+	f.Size, f.Location = cr.n, location
+
+	return f, nil
+}
+
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range allowed {
+		if a == mt || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func uploadError(err error) error {
+	var (
+		herr        *Error
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &herr):
+		return err
+	case errors.As(err, &maxBytesErr):
+		return &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit),
+			Err:     err,
+		}
+	default:
+		return &Error{Status: http.StatusBadRequest, Message: "malformed multipart request", Err: err}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type diskDestination struct{ dir string }
+
+// DiskDestination stores files in dir under random names keeping the original extension.
+func DiskDestination(dir string) Destination { return diskDestination{dir: dir} }
+
+func (d diskDestination) Save(_ context.Context, f *UploadedFile, r io.Reader) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(d.dir, hex.EncodeToString(b)+strings.ToLower(filepath.Ext(f.Filename)))
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		_ = os.Remove(path)
+		return "", err
+	}
+
+	if err := out.Close(); err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (d diskDestination) Remove(_ context.Context, location string) error {
+	return os.Remove(location)
+}
+
+type writerDestination struct{ w io.Writer }
+
+// WriterDestination copies every file into w, e.g. a hash or a pipe. Files cannot be removed once written.
+func WriterDestination(w io.Writer) Destination { return writerDestination{w: w} }
+
+func (d writerDestination) Save(_ context.Context, f *UploadedFile, r io.Reader) (string, error) {
+	_, err := io.Copy(d.w, r)
+	return f.Filename, err
+}
+
+func (writerDestination) Remove(context.Context, string) error { return nil }
+
+type blobDestination struct {
+	store BlobStore
+	key   func(*UploadedFile) string
+}
+
+// BlobDestination streams files to store under the key returned by key.
+func BlobDestination(store BlobStore, key func(*UploadedFile) string) Destination {
+	return blobDestination{store: store, key: key}
+}
+
+func (d blobDestination) Save(ctx context.Context, f *UploadedFile, r io.Reader) (string, error) {
+	key := d.key(f)
+	return key, d.store.Put(ctx, key, r, f.ContentType)
+}
+
+func (d blobDestination) Remove(ctx context.Context, location string) error {
+	return d.store.Delete(ctx, location)
+}
+
+type UploadOption interface {
+	applyToUpload(*uploadConfig)
+}
+
+type UploadOptionFunc func(*uploadConfig)
+
+func (f UploadOptionFunc) applyToUpload(c *uploadConfig) { f(c) }
+
+// WithUploadMaxBytes limits the size of the whole request body.
+func WithUploadMaxBytes(n int64) UploadOptionFunc {
+	return func(c *uploadConfig) { c.maxBytes = n }
+}
+
+func WithUploadMaxFileBytes(n int64) UploadOptionFunc {
+	return func(c *uploadConfig) { c.maxFileBytes = n }
+}
+
+func WithUploadMaxFiles(n int) UploadOptionFunc {
+	return func(c *uploadConfig) { c.maxFiles = n }
+}
+
+// WithUploadAllowedTypes restricts files to the given sniffed media types. Types may end in /* such as "image/*".
+func WithUploadAllowedTypes(types ...string) UploadOptionFunc {
+	return func(c *uploadConfig) { c.allowedTypes = append(c.allowedTypes, types...) }
+}
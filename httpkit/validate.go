@@ -0,0 +1,281 @@
+package httpkit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every invalid field of a request. It is rendered as a 422 problem with the fields listed
+// in the "errors" extension member.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Add(field, msg string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: msg})
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Problem() *problem.Details {
+	return problem.New(http.StatusUnprocessableEntity).
+		WithDetail("request validation failed").
+		With("errors", e.Fields)
+}
+
+// Validator is implemented by request types with rules that struct tags cannot express.
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks the `validate` struct tags of v, then calls its Validate method if it implements Validator. The
+// supported comma-separated rules are required, min=n, max=n, len=n, oneof=a b c and email, where min, max and len
+// apply to the value of numbers and the length of strings, slices and maps. Fields are named after their json,
+// query, path or header tag. Malformed rules are reported as an error on the first call for a type.
+func Validate(v any) error {
+	var verr ValidationError
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() == reflect.Struct {
+		if err := checkValidateType(rv.Type()); err != nil {
+			return err
+		}
+		validateStruct(rv, "", &verr)
+	}
+
+	if val, ok := v.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			var other *ValidationError
+			if !errors.As(err, &other) {
+				return err
+			}
+			verr.Fields = append(verr.Fields, other.Fields...)
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return &verr
+	}
+
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, verr *ValidationError) {
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldName(sf)
+		fv := rv.Field(i)
+
+		if rules := sf.Tag.Get("validate"); rules != "" && rules != "-" {
+			if msg := checkRules(fv, rules); msg != "" {
+				verr.Add(name, msg)
+				continue
+			}
+		}
+
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != _timeType {
+			validateStruct(fv, name+".", verr)
+		}
+	}
+}
+
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "query", "path", "header"} {
+		if tag := sf.Tag.Get(key); tag != "" && tag != "-" {
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				return name
+			}
+		}
+	}
+	return sf.Name
+}
+
+// checkRules returns the message for the first rule fv violates, or "" if it satisfies all of them.
+func checkRules(fv reflect.Value, rules string) string {
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if name == "required" {
+			if fv.IsZero() {
+				return "is required"
+			}
+			continue
+		}
+
+		v := fv
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+
+		if msg := checkRule(v, name, arg); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func checkRule(v reflect.Value, name, arg string) string {
+	switch name {
+	case "min", "max", "len":
+		limit, _ := strconv.ParseFloat(arg, 64)
+
+		n, unit := measure(v)
+		switch {
+		case name == "min" && n < limit && unit != "":
+			return fmt.Sprintf("must have at least %s %s", arg, unit)
+		case name == "min" && n < limit:
+			return fmt.Sprintf("must be at least %s", arg)
+		case name == "max" && n > limit && unit != "":
+			return fmt.Sprintf("must have at most %s %s", arg, unit)
+		case name == "max" && n > limit:
+			return fmt.Sprintf("must be at most %s", arg)
+		case name == "len" && n != limit:
+			return fmt.Sprintf("must have exactly %s %s", arg, unit)
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		if !v.IsZero() && !slices.Contains(options, fmt.Sprint(v.Interface())) {
+			return "must be one of " + strings.Join(options, ", ")
+		}
+	case "email":
+		if v.Kind() == reflect.String && v.String() != "" {
+			if addr, err := mail.ParseAddress(v.String()); err != nil || addr.Address != v.String() {
+				return "must be a valid email address"
+			}
+		}
+	}
+
+	return ""
+}
+
+// measure returns the numeric value of numbers, or the length of strings, slices and maps with its unit.
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), "elements"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	default:
+		// Unreachable for types accepted by checkValidateType.
+		return 0, ""
+	}
+}
+
+// _validateTypes caches the result of checkValidateType by struct type.
+var _validateTypes sync.Map
+
+// checkValidateType reports unknown rules, invalid arguments and range rules on fields that cannot be measured in rt
+// and the structs it nests, once per type, so validateStruct can rely on the rules being well-formed.
+func checkValidateType(rt reflect.Type) error {
+	if err, ok := _validateTypes.Load(rt); ok {
+		return typeError(err)
+	}
+
+	err := checkRuleTags(rt, map[reflect.Type]bool{})
+	_validateTypes.Store(rt, err)
+	return err
+}
+
+func checkRuleTags(rt reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[rt] {
+		return nil
+	}
+	seen[rt] = true
+
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if rules := sf.Tag.Get("validate"); rules != "" && rules != "-" {
+			for _, rule := range strings.Split(rules, ",") {
+				if err := checkRuleTag(ft, strings.TrimSpace(rule)); err != nil {
+					return fmt.Errorf("httpkit: field %s.%s: %w", rt, sf.Name, err)
+				}
+			}
+		}
+
+		if ft.Kind() == reflect.Struct && ft != _timeType {
+			if err := checkRuleTags(ft, seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkRuleTag(ft reflect.Type, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	switch name {
+	case "required", "oneof", "email":
+		return nil
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("invalid %s rule argument %q", name, arg)
+		}
+		switch ft.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return nil
+		default:
+			return fmt.Errorf("cannot apply %s rule to %s", name, ft)
+		}
+	default:
+		return fmt.Errorf("unknown validation rule %q", name)
+	}
+}
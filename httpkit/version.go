@@ -0,0 +1,180 @@
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+const _defaultVersionParam = "version"
+
+type apiVersion struct {
+	handler    http.Handler
+	deprecated time.Time
+	sunset     time.Time
+	link       string
+}
+
+// VersionMux dispatches requests to the handler of their API version. The version is taken from a "/v{version}"
+// path prefix, which is stripped, or else from a media type parameter of the Accept header such as
+// "application/json; version=2", or else the default version. The resolved version is available through APIVersion.
+type VersionMux struct {
+	mu         sync.RWMutex
+	versions   map[string]*apiVersion
+	def        string
+	param      string
+	pathPrefix bool
+}
+
+var apiVersionKey = NewContextKey[string]("api version")
+
+// APIVersion returns the version resolved by a VersionMux, or "" outside of one.
+func APIVersion(ctx context.Context) string {
+	return ContextValue(ctx, apiVersionKey)
+}
+
+func NewVersionMux(opts ...VersionOption) *VersionMux {
+	m := VersionMux{versions: make(map[string]*apiVersion), param: _defaultVersionParam, pathPrefix: true}
+	for _, opt := range opts {
+		opt.applyToVersionMux(&m)
+	}
+	return &m
+}
+
+// Handle registers h for version, e.g. "1" or "2024-01-01". It panics if the version is already registered.
+func (m *VersionMux) Handle(version string, h http.Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.versions[version]; ok {
+		panic(fmt.Sprintf("httpkit: API version %q already registered", version))
+	}
+
+	m.versions[version] = &apiVersion{handler: h}
+}
+
+// Deprecate marks version as deprecated since the given time and, unless sunset is zero, announces its removal.
+// Responses of the version then carry the Deprecation (RFC 9745) and Sunset (RFC 8594) headers and, if link is set,
+// a Link to the migration guide.
+func (m *VersionMux) Deprecate(version string, deprecated, sunset time.Time, link string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.versions[version]
+	if !ok {
+		panic(fmt.Sprintf("httpkit: API version %q is not registered", version))
+	}
+
+	v.deprecated, v.sunset, v.link = deprecated, sunset, link
+}
+
+func (m *VersionMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The entry is copied so the lock is not held while the request is served, which would block Handle and
+	// Deprecate, and every request behind them, until the longest running request ends.
+	m.mu.RLock()
+	version, rest, fromPath := m.pathVersion(r.URL.Path)
+	if !fromPath {
+		version = m.acceptVersion(r)
+	}
+	if version == "" {
+		version = m.def
+	}
+	entry, ok := m.versions[version]
+	var v apiVersion
+	if ok {
+		v = *entry
+	}
+	m.mu.RUnlock()
+
+	if !fromPath {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	if !ok {
+		_ = problem.Write(w, problem.New(http.StatusNotFound).
+			WithDetail(fmt.Sprintf("API version %q does not exist", version)).
+			WithInstance(r.URL.Path))
+		return
+	}
+
+	if fromPath {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path, r2.URL.RawPath = rest, ""
+		r = r2
+	}
+
+	if !v.deprecated.IsZero() {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(v.deprecated.Unix(), 10))
+		if v.link != "" {
+			w.Header().Add("Link", "<"+v.link+`>; rel="deprecation"`)
+		}
+	}
+	if !v.sunset.IsZero() {
+		w.Header().Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+	}
+
+	v.handler.ServeHTTP(w, r.WithContext(ContextSet(r.Context(), apiVersionKey, version)))
+}
+
+// pathVersion returns the version of a registered "/v{version}" prefix and the path without it.
+func (m *VersionMux) pathVersion(path string) (version, rest string, ok bool) {
+	if !m.pathPrefix {
+		return "", "", false
+	}
+
+	seg, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	version, ok = strings.CutPrefix(seg, "v")
+	if !ok {
+		return "", "", false
+	}
+
+	if _, ok := m.versions[version]; !ok {
+		return "", "", false
+	}
+
+	return version, "/" + rest, true
+}
+
+// acceptVersion returns the version parameter of the first media range in the Accept header carrying one.
+func (m *VersionMux) acceptVersion(r *http.Request) string {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if v := params[m.param]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type VersionOption interface {
+	applyToVersionMux(*VersionMux)
+}
+
+type VersionOptionFunc func(*VersionMux)
+
+func (f VersionOptionFunc) applyToVersionMux(m *VersionMux) { f(m) }
+
+// WithDefaultVersion sets the version of requests that do not ask for one. Without it, they are answered with 404.
+func WithDefaultVersion(version string) VersionOptionFunc {
+	return func(m *VersionMux) { m.def = version }
+}
+
+// WithVersionParam changes the Accept media type parameter carrying the version, "version" by default.
+func WithVersionParam(name string) VersionOptionFunc {
+	return func(m *VersionMux) { m.param = name }
+}
+
+// WithoutVersionPathPrefix only resolves versions from the Accept header.
+func WithoutVersionPathPrefix() VersionOptionFunc {
+	return func(m *VersionMux) { m.pathPrefix = false }
+}
@@ -0,0 +1,61 @@
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/httpkit/problem"
+)
+
+const _warmupRetryAfter = 5 * time.Second
+
+type warmupOption struct{ value func(context.Context) error }
+
+// WithWarmup runs fn once the listener is open but before traffic is handled, e.g. to apply pgxkit migrations or
+// prime caches. Until all warmup functions returned, requests are answered with 503 Service Unavailable and a
+// Retry-After header, so readiness checks fail, while /livez is passed through. Warmup functions run in the order
+// they were given; if one fails, Serve shuts down and returns its error.
+func WithWarmup(fn func(ctx context.Context) error) ConfigOption { return warmupOption{value: fn} }
+
+func (o warmupOption) applyToConfig(cfg *Config) { cfg.warmup = append(cfg.warmup, o.value) }
+
+// warmupGate answers requests with 503 until its warmup functions completed.
+type warmupGate struct {
+	warming atomic.Bool
+	fns     []func(context.Context) error
+}
+
+func newWarmupGate(fns []func(context.Context) error) *warmupGate {
+	g := warmupGate{fns: fns}
+	g.warming.Store(len(fns) > 0)
+	return &g
+}
+
+func (g *warmupGate) run(ctx context.Context) error {
+	for _, fn := range g.fns {
+		if err := fn(ctx); err != nil {
+			return fmt.Errorf("warming up: %w", err)
+		}
+	}
+
+	g.warming.Store(false)
+	return nil
+}
+
+func (g *warmupGate) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.warming.Load() && r.URL.Path != "/livez" {
+			w.Header().Set("Retry-After", strconv.Itoa(int(_warmupRetryAfter.Seconds())))
+			_ = problem.Write(w, problem.New(http.StatusServiceUnavailable).
+				WithDetail("service is starting").
+				WithInstance(r.URL.Path))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,255 @@
+// Package ws upgrades HTTP requests to WebSocket connections on top of github.com/coder/websocket, adding keepalive
+// pings, read limits, JSON messages and closing all connections on server shutdown.
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	_defaultPingInterval = 30 * time.Second
+	_defaultPingTimeout  = 10 * time.Second
+	_defaultReadLimit    = 1 << 20
+)
+
+var ErrShuttingDown = errors.New("ws: server is shutting down")
+
+type config struct {
+	origins      []string
+	subprotocols []string
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+	readLimit    int64
+}
+
+// Upgrader accepts WebSocket connections and keeps track of them so they can all be closed on shutdown, since the
+// HTTP server no longer manages hijacked connections.
+type Upgrader struct {
+	cfg     config
+	mu      sync.Mutex
+	conns   map[*Conn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func NewUpgrader(opts ...Option) *Upgrader {
+	cfg := config{
+		pingInterval: _defaultPingInterval,
+		pingTimeout:  _defaultPingTimeout,
+		readLimit:    _defaultReadLimit,
+	}
+
+	for _, opt := range opts {
+		opt.applyToConfig(&cfg)
+	}
+
+	return &Upgrader{cfg: cfg, conns: make(map[*Conn]struct{})}
+}
+
+// Upgrade completes the WebSocket handshake. Requests from an origin other than the request host are rejected with
+// 403 unless allowed by WithOrigins. On failure a response has already been written. The handler must close the
+// connection when done, typically with defer c.CloseNow().
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	u.mu.Lock()
+	closing := u.closing
+	u.mu.Unlock()
+
+	if closing {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return nil, ErrShuttingDown
+	}
+
+	wc, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: u.cfg.origins,
+		Subprotocols:   u.cfg.subprotocols,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wc.SetReadLimit(u.cfg.readLimit)
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	c := &Conn{Conn: wc, ctx: ctx, cancel: cancel, u: u}
+
+	u.mu.Lock()
+	if u.closing {
+		u.mu.Unlock()
+		_ = wc.Close(websocket.StatusGoingAway, "server shutting down")
+		cancel()
+		return nil, ErrShuttingDown
+	}
+	u.conns[c] = struct{}{}
+	u.wg.Add(1)
+	u.mu.Unlock()
+
+	if u.cfg.pingInterval > 0 {
+		go c.keepalive(u.cfg.pingInterval, u.cfg.pingTimeout)
+	}
+
+	return c, nil
+}
+
+// CloseAll closes every connection with StatusGoingAway and rejects new upgrades. Pass it to httpkit.WithOnShutdown
+// to close connections when the server shuts down.
+func (u *Upgrader) CloseAll() {
+	u.mu.Lock()
+	u.closing = true
+	conns := make([]*Conn, 0, len(u.conns))
+	for c := range u.conns {
+		conns = append(conns, c)
+	}
+	u.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.Close(websocket.StatusGoingAway, "server shutting down")
+	}
+}
+
+// Wait blocks until all connections are closed or ctx is done.
+func (u *Upgrader) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		u.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Len returns the number of open connections.
+func (u *Upgrader) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.conns)
+}
+
+func (u *Upgrader) remove(c *Conn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if _, ok := u.conns[c]; ok {
+		delete(u.conns, c)
+		u.wg.Done()
+	}
+}
+
+// Conn is an accepted WebSocket connection. Pings are only answered while a goroutine is reading, so handlers must
+// keep reading, or call CloseRead if they only write.
+type Conn struct {
+	*websocket.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+	u      *Upgrader
+}
+
+// Context is canceled once the connection is closed, including when a keepalive ping is not answered in time.
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *Conn) Close(code websocket.StatusCode, reason string) error {
+	defer c.closed()
+	return c.Conn.Close(code, reason)
+}
+
+func (c *Conn) CloseNow() error {
+	defer c.closed()
+	return c.Conn.CloseNow()
+}
+
+func (c *Conn) closed() {
+	c.cancel()
+	c.u.remove(c)
+}
+
+// ReadJSON reads the next text or binary message and decodes it into v.
+func (c *Conn) ReadJSON(ctx context.Context, v any) error {
+	_, b, err := c.Read(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		_ = c.Close(websocket.StatusUnsupportedData, "invalid JSON")
+		return fmt.Errorf("decoding message: %w", err)
+	}
+
+	return nil
+}
+
+// WriteJSON encodes v as JSON and writes it as a text message.
+func (c *Conn) WriteJSON(ctx context.Context, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+	return c.Write(ctx, websocket.MessageText, b)
+}
+
+func (c *Conn) keepalive(interval, timeout time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(c.ctx, timeout)
+			err := c.Ping(ctx)
+			cancel()
+
+			if err != nil {
+				_ = c.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// CloseStatus returns the close code sent by the peer if err was caused by the connection being closed, or -1.
+func CloseStatus(err error) websocket.StatusCode {
+	return websocket.CloseStatus(err)
+}
+
+type Option interface {
+	applyToConfig(*config)
+}
+
+type OptionFunc func(*config)
+
+func (f OptionFunc) applyToConfig(c *config) { f(c) }
+
+// WithOrigins allows cross-origin connections from hosts matching the given path.Match patterns.
+func WithOrigins(patterns ...string) OptionFunc {
+	return func(c *config) { c.origins = append(c.origins, patterns...) }
+}
+
+func WithSubprotocols(protocols ...string) OptionFunc {
+	return func(c *config) { c.subprotocols = append(c.subprotocols, protocols...) }
+}
+
+// WithPing sets how often connections are pinged and how long the peer has to answer. A zero interval disables
+// keepalive pings.
+func WithPing(interval, timeout time.Duration) OptionFunc {
+	return func(c *config) { c.pingInterval, c.pingTimeout = interval, timeout }
+}
+
+// WithReadLimit sets the maximum size of a message read from the peer. The default is 1 MiB.
+func WithReadLimit(n int64) OptionFunc {
+	return func(c *config) { c.readLimit = n }
+}
@@ -0,0 +1,203 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrLockNotAcquired is returned when an advisory lock is held elsewhere and waiting for it was not requested.
+var ErrLockNotAcquired = errors.New("advisory lock not acquired")
+
+// AdvisoryLockKey derives the key of an advisory lock from a name, so locks can be named instead of numbered.
+func AdvisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock is a session-level advisory lock holding the connection it was acquired on until released.
+type AdvisoryLock struct {
+	conn   *pgxpool.Conn
+	key    int64
+	shared bool
+}
+
+// AcquireAdvisoryLock waits for the session-level advisory lock key on a connection of its own.
+func AcquireAdvisoryLock(ctx context.Context, db Acquirer, key int64, opts ...AdvisoryLockOption) (*AdvisoryLock, error) {
+	cfg := newLockConfig(opts)
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring connection: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT "+cfg.fn("pg_advisory_lock")+"($1)", key); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("acquiring advisory lock: %w", mapErr(err))
+	}
+
+	return &AdvisoryLock{conn: conn, key: key, shared: cfg.shared}, nil
+}
+
+// TryAdvisoryLock takes the session-level advisory lock key if it is free. It returns ErrLockNotAcquired otherwise.
+func TryAdvisoryLock(ctx context.Context, db Acquirer, key int64, opts ...AdvisoryLockOption) (*AdvisoryLock, error) {
+	cfg := newLockConfig(opts)
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring connection: %w", err)
+	}
+
+	var ok bool
+	if err := conn.QueryRow(ctx, "SELECT "+cfg.fn("pg_try_advisory_lock")+"($1)", key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("acquiring advisory lock: %w", mapErr(err))
+	}
+
+	if !ok {
+		conn.Release()
+		return nil, ErrLockNotAcquired
+	}
+
+	return &AdvisoryLock{conn: conn, key: key, shared: cfg.shared}, nil
+}
+
+// Release unlocks and returns the connection to the pool. If unlocking fails the connection is closed, which releases
+// the lock as well, so the lock never outlives Release. Releasing twice is a no-op.
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+
+	conn := l.conn
+	l.conn = nil
+
+	fn := "pg_advisory_unlock"
+	if l.shared {
+		fn = "pg_advisory_unlock_shared"
+	}
+
+	var unlocked bool
+	err := conn.QueryRow(context.WithoutCancel(ctx), "SELECT "+fn+"($1)", l.key).Scan(&unlocked)
+	if err != nil || !unlocked {
+		_ = conn.Hijack().Close(context.WithoutCancel(ctx))
+		if err != nil {
+			return fmt.Errorf("releasing advisory lock: %w", err)
+		}
+		return nil
+	}
+
+	conn.Release()
+	return nil
+}
+
+// Conn returns the connection holding the lock, e.g. to run statements while holding it.
+func (l *AdvisoryLock) Conn() *pgxpool.Conn { return l.conn }
+
+// WithAdvisoryLock runs fn while holding the advisory lock key and releases it afterwards, also when fn panics. By
+// default the lock is session-level and waited for; WithLockTransaction takes it in a transaction instead, which fn
+// then runs in with the transaction in its context for Runner, and WithLockTry returns ErrLockNotAcquired rather than
+// waiting.
+func WithAdvisoryLock(ctx context.Context, db DB, key int64, fn func(ctx context.Context) error, opts ...AdvisoryLockOption) error {
+	cfg := newLockConfig(opts)
+
+	if cfg.transaction {
+		return WithTx(ctx, db, func(tx Tx) error {
+			if err := lockTx(ctx, tx, key, cfg); err != nil {
+				return err
+			}
+			return fn(TxToContext(ctx, tx))
+		})
+	}
+
+	acquire := AcquireAdvisoryLock
+	if cfg.try {
+		acquire = TryAdvisoryLock
+	}
+
+	lock, err := acquire(ctx, db, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		defer func() {
+			if rec := recover(); rec != nil {
+				_ = lock.Release(ctx)
+				panic(rec)
+			}
+		}()
+		return fn(ctx)
+	}()
+
+	return errors.Join(err, lock.Release(ctx))
+}
+
+// lockTx takes the transaction-level lock key, which is released when the transaction ends.
+func lockTx(ctx context.Context, tx Tx, key int64, cfg lockConfig) error {
+	if !cfg.try {
+		if _, err := tx.Exec(ctx, "SELECT "+cfg.fn("pg_advisory_xact_lock")+"($1)", key); err != nil {
+			return fmt.Errorf("acquiring advisory lock: %w", err)
+		}
+		return nil
+	}
+
+	var ok bool
+	if err := tx.QueryRow(ctx, "SELECT "+cfg.fn("pg_try_advisory_xact_lock")+"($1)", key).Scan(&ok); err != nil {
+		return fmt.Errorf("acquiring advisory lock: %w", err)
+	}
+	if !ok {
+		return ErrLockNotAcquired
+	}
+	return nil
+}
+
+type lockConfig struct {
+	shared      bool
+	transaction bool
+	try         bool
+}
+
+func newLockConfig(opts []AdvisoryLockOption) lockConfig {
+	var cfg lockConfig
+	for _, opt := range opts {
+		opt.applyToLock(&cfg)
+	}
+	return cfg
+}
+
+// fn returns the name of the lock function, with the _shared suffix for shared locks.
+func (c lockConfig) fn(name string) string {
+	if c.shared {
+		return name + "_shared"
+	}
+	return name
+}
+
+type AdvisoryLockOption interface {
+	applyToLock(*lockConfig)
+}
+
+type AdvisoryLockOptionFunc func(*lockConfig)
+
+func (f AdvisoryLockOptionFunc) applyToLock(c *lockConfig) { f(c) }
+
+// WithLockShared takes the lock in shared mode, which excludes only exclusive holders.
+func WithLockShared() AdvisoryLockOptionFunc {
+	return func(c *lockConfig) { c.shared = true }
+}
+
+// WithLockTransaction makes WithAdvisoryLock take a transaction-level lock. It has no effect on AcquireAdvisoryLock
+// and TryAdvisoryLock, which always take session-level locks.
+func WithLockTransaction() AdvisoryLockOptionFunc {
+	return func(c *lockConfig) { c.transaction = true }
+}
+
+// WithLockTry makes WithAdvisoryLock fail with ErrLockNotAcquired instead of waiting for the lock.
+func WithLockTry() AdvisoryLockOptionFunc {
+	return func(c *lockConfig) { c.try = true }
+}
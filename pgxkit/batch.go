@@ -0,0 +1,101 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Batch queues statements whose results are collected into typed BatchResults when the batch is sent, in one round
+// trip. Statements are queued with BatchQuery, BatchQueryRow, BatchQueryValue and BatchExec.
+type Batch struct {
+	batch      pgx.Batch
+	collectors []func(pgx.BatchResults) error
+}
+
+func NewBatch() *Batch { return &Batch{} }
+
+func (b *Batch) Len() int { return b.batch.Len() }
+
+// BatchResult holds the result of a queued statement once the batch was sent.
+type BatchResult[T any] struct {
+	val  T
+	err  error
+	sent bool
+}
+
+var errBatchNotSent = errors.New("batch not sent")
+
+// Value returns the result of the statement, or its error.
+func (r *BatchResult[T]) Value() (T, error) {
+	if !r.sent {
+		var zero T
+		return zero, errBatchNotSent
+	}
+	return r.val, r.err
+}
+
+func queue[T any](b *Batch, sql string, args []any, collect func(pgx.BatchResults) (T, error)) *BatchResult[T] {
+	var res BatchResult[T]
+
+	b.batch.Queue(sql, args...)
+	b.collectors = append(b.collectors, func(br pgx.BatchResults) error {
+		res.val, res.err = collect(br)
+		res.err = mapErr(res.err)
+		res.sent = true
+		return res.err
+	})
+
+	return &res
+}
+
+// BatchQuery queues a query whose rows are scanned into structs by name, like Query.
+func BatchQuery[T any](b *Batch, sql string, args ...any) *BatchResult[[]T] {
+	return queue(b, sql, args, func(br pgx.BatchResults) ([]T, error) {
+		rows, _ := br.Query()
+		return pgx.CollectRows(rows, pgx.RowToStructByName[T])
+	})
+}
+
+// BatchQueryRow queues a query whose single row is scanned into a struct by name, like QueryRow.
+func BatchQueryRow[T any](b *Batch, sql string, args ...any) *BatchResult[T] {
+	return queue(b, sql, args, func(br pgx.BatchResults) (T, error) {
+		rows, _ := br.Query()
+		return pgx.CollectOneRow(rows, pgx.RowToStructByName[T])
+	})
+}
+
+// BatchQueryValue queues a query returning exactly one value, like QueryValue.
+func BatchQueryValue[T any](b *Batch, sql string, args ...any) *BatchResult[T] {
+	return queue(b, sql, args, func(br pgx.BatchResults) (T, error) {
+		rows, _ := br.Query()
+		return pgx.CollectExactlyOneRow(rows, pgx.RowTo[T])
+	})
+}
+
+func BatchExec(b *Batch, sql string, args ...any) *BatchResult[pgconn.CommandTag] {
+	return queue(b, sql, args, func(br pgx.BatchResults) (pgconn.CommandTag, error) {
+		return br.Exec()
+	})
+}
+
+// Send sends the batch and collects all results in order, closing the batch results afterwards. It returns the first
+// error of a statement, or the error closing the batch; every BatchResult carries its own error.
+func (b *Batch) Send(ctx context.Context, db BatchSender) error {
+	br := db.SendBatch(ctx, &b.batch)
+
+	var first error
+	for _, collect := range b.collectors {
+		if err := collect(br); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	if err := br.Close(); err != nil && first == nil {
+		first = mapErr(err)
+	}
+
+	return first
+}
@@ -2,16 +2,23 @@ package pgxkit
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"math"
+	"math/rand/v2"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jackc/tern/v2/migrate"
 )
 
+const _maxConnectBackoff = 30 * time.Second
+
 type pool = pgxpool.Pool
 
 type client struct {
@@ -20,6 +27,18 @@ type client struct {
 	opened        bool
 	migrations    fs.FS
 	migrateAction MigrateActionFlag
+	poolConfig    []func(*pgxpool.Config)
+	afterConnect  []func(context.Context, *pgx.Conn) error
+	loadTLS       func() (*tls.Config, error)
+	connAttempts  int
+	connBackoff   time.Duration
+	tracers       []pgx.QueryTracer
+	queryLog      []QueryLoggerOption
+	queryLogOn    bool
+	metrics       *poolMetrics
+	slowQuery     time.Duration
+	healthQuery   bool
+	healthTimeout time.Duration
 	*pool
 }
 
@@ -36,11 +55,45 @@ func (c *client) Open(ctx context.Context) error {
 		return nil
 	}
 
-	db, err := Open(ctx, c.url)
+	cfg, err := pgxpool.ParseConfig(c.url)
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range c.poolConfig {
+		fn(cfg)
+	}
+
+	cfg.AfterConnect = c.afterConnectHook(cfg.AfterConnect)
+
+	if c.loadTLS != nil {
+		tlsConfig, err := c.loadTLS()
+		if err != nil {
+			return err
+		}
+		applyTLS(&cfg.ConnConfig.Config, tlsConfig)
+	}
+
+	if c.metrics != nil {
+		if err := c.metrics.registerQueries(); err != nil {
+			return fmt.Errorf("registering metrics: %w", err)
+		}
+	}
+
+	cfg.ConnConfig.Tracer = c.tracer(cfg.ConnConfig.Tracer)
+
+	db, err := c.openPool(ctx, cfg)
 	if err != nil {
 		return err
 	}
 
+	if c.metrics != nil {
+		if err := c.metrics.registerPool(db); err != nil {
+			db.Close()
+			return fmt.Errorf("registering metrics: %w", err)
+		}
+	}
+
 	c.pool = db
 	c.opened = true
 
@@ -55,6 +108,155 @@ func (c *client) Open(ctx context.Context) error {
 	return nil
 }
 
+// openPool opens the pool, retrying as configured with WithConnectRetry while the database cannot be reached.
+func (c *client) openPool(ctx context.Context, cfg *pgxpool.Config) (*pgxpool.Pool, error) {
+	for attempt := 1; ; attempt++ {
+		db, err := OpenConfig(ctx, cfg)
+		if err == nil || attempt >= c.connAttempts || ctx.Err() != nil {
+			return db, err
+		}
+
+		var d time.Duration
+		if c.connBackoff > 0 {
+			d = c.connBackoff << (attempt - 1)
+			if d <= 0 || d > _maxConnectBackoff {
+				d = _maxConnectBackoff
+			}
+			d = d/2 + rand.N(d/2+1)
+		}
+
+		if c.log != nil {
+			c.log.WarnContext(ctx, "connecting to database",
+				slog.Int("attempt", attempt),
+				slog.Duration("retry_in", d),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-t.C:
+		}
+	}
+}
+
+// tracer combines the tracer already configured, e.g. through WithPoolConfig, with those added by options.
+func (c *client) tracer(configured pgx.QueryTracer) pgx.QueryTracer {
+	var tracers []pgx.QueryTracer
+	if configured != nil {
+		tracers = append(tracers, configured)
+	}
+
+	log := c.log
+	if log == nil {
+		log = slog.Default()
+	}
+
+	if c.queryLogOn {
+		tracers = append(tracers, NewQueryLogger(log, c.queryLog...))
+	}
+
+	if c.slowQuery > 0 {
+		tracers = append(tracers, &slowQueryLogger{log: log, threshold: c.slowQuery})
+	}
+
+	if c.metrics != nil {
+		tracers = append(tracers, c.metrics)
+	}
+
+	return newTracer(append(tracers, c.tracers...))
+}
+
+// afterConnectHook runs the hook already configured, e.g. through WithPoolConfig, followed by those added by options.
+func (c *client) afterConnectHook(configured func(context.Context, *pgx.Conn) error) func(context.Context, *pgx.Conn) error {
+	hooks := c.afterConnect
+	if configured != nil {
+		hooks = append([]func(context.Context, *pgx.Conn) error{configured}, hooks...)
+	}
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		for _, fn := range hooks {
+			if err := fn(ctx, conn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// HealthCheck pings the pool and, if enabled with WithHealthQuery, runs "SELECT 1" within the configured timeout.
+func (c *client) HealthCheck(ctx context.Context) error {
+	if !c.opened {
+		return errors.New("client is not opened")
+	}
+
+	if err := c.Ping(ctx); err != nil {
+		return fmt.Errorf("pinging database: %w", err)
+	}
+
+	if !c.healthQuery {
+		return nil
+	}
+
+	if c.healthTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.healthTimeout)
+		defer cancel()
+	}
+
+	if _, err := QueryValue[int](ctx, c, "SELECT 1"); err != nil {
+		return fmt.Errorf("querying database: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the pool if the client is open, after which it can be opened again.
+func (c *client) Close() {
+	if !c.opened {
+		return
+	}
+	c.pool.Close()
+	c.opened = false
+	c.unregisterMetrics()
+}
+
+// Shutdown closes the pool gracefully: new acquires fail right away, while connections in use are waited for until
+// they are released or ctx is done. Connections released after that are closed as they are released.
+func (c *client) Shutdown(ctx context.Context) error {
+	if !c.opened {
+		return nil
+	}
+	c.opened = false
+	c.unregisterMetrics()
+
+	done := make(chan struct{})
+	go func() {
+		c.pool.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for connections in use: %w", ctx.Err())
+	}
+}
+
+func (c *client) unregisterMetrics() {
+	if c.metrics != nil {
+		c.metrics.unregisterPool()
+	}
+}
+
 func (c *client) Conn(ctx context.Context) (*pgx.Conn, error) {
 	conn, err := c.Acquire(ctx)
 	if err != nil {
@@ -63,6 +265,8 @@ func (c *client) Conn(ctx context.Context) (*pgx.Conn, error) {
 	return conn.Hijack(), nil
 }
 
+// MigrateAction is what Migrate does: "up" to the latest version, "down" to version 0, or one of "to:N", "up:N" and
+// "down:N" created by MigrateTo, MigrateUpBy and MigrateDownBy.
 type MigrateAction string
 
 const (
@@ -70,6 +274,45 @@ const (
 	MigrateDown MigrateAction = "down"
 )
 
+// MigrateTo migrates up or down to version, 0 undoing all migrations.
+func MigrateTo(version int32) MigrateAction { return MigrateAction("to:" + strconv.Itoa(int(version))) }
+
+// MigrateUpBy applies the next n migrations, or those left if fewer.
+func MigrateUpBy(n int32) MigrateAction { return MigrateAction("up:" + strconv.Itoa(int(n))) }
+
+// MigrateDownBy undoes the last n migrations, or all of them if fewer were applied.
+func MigrateDownBy(n int32) MigrateAction { return MigrateAction("down:" + strconv.Itoa(int(n))) }
+
+// target returns the version act migrates to from current, with last the latest version.
+func (act MigrateAction) target(current, last int32) (int32, error) {
+	switch act {
+	case MigrateUp:
+		return last, nil
+	case MigrateDown:
+		return 0, nil
+	}
+
+	kind, arg, ok := strings.Cut(string(act), ":")
+	n, err := strconv.ParseInt(arg, 10, 32)
+	if !ok || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid migrate action: %s", act)
+	}
+
+	switch kind {
+	case "to":
+		if int32(n) > last {
+			return 0, fmt.Errorf("migrating to version %d: latest version is %d", n, last)
+		}
+		return int32(n), nil
+	case "up":
+		return min(current+int32(n), last), nil
+	case "down":
+		return max(current-int32(n), 0), nil
+	default:
+		return 0, fmt.Errorf("invalid migrate action: %s", act)
+	}
+}
+
 const (
 	_defaultVersionTable = "public.schema_version"
 	_defaultSubtree      = "migrations"
@@ -87,20 +330,9 @@ func (c *client) Migrate(ctx context.Context, fsys fs.FS, act MigrateAction) err
 	}
 	defer c.closeConn(ctx, conn)
 
-	if c.hasNestedFS(fsys) {
-		fsys, err = fs.Sub(fsys, _defaultSubtree)
-		if err != nil {
-			return fmt.Errorf("sub migrations directory: %w", err)
-		}
-	}
-
-	mg, err := migrate.NewMigrator(ctx, conn, _defaultVersionTable)
+	mg, err := c.migrator(ctx, conn, fsys)
 	if err != nil {
-		return fmt.Errorf("creating migrator: %w", err)
-	}
-
-	if err := mg.LoadMigrations(fsys); err != nil {
-		return fmt.Errorf("load migrations: %w", err)
+		return err
 	}
 
 	if c.log != nil {
@@ -109,14 +341,20 @@ func (c *client) Migrate(ctx context.Context, fsys fs.FS, act MigrateAction) err
 		}
 	}
 
-	switch act {
-	case MigrateUp:
-		return mg.Migrate(ctx)
-	case MigrateDown:
-		return mg.MigrateTo(ctx, 0)
-	default:
-		return fmt.Errorf("invalid migrate action: %s", act)
+	current, err := mg.GetCurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("getting current version: %w", err)
 	}
+
+	target, err := act.target(current, int32(len(mg.Migrations)))
+	if err != nil {
+		return err
+	}
+
+	err = mg.MigrateTo(ctx, target)
+
+	// Migrations applied before a failure stay applied, so the history is recorded either way.
+	return errors.Join(err, recordHistory(ctx, conn, versionTable(fsys)+_historySuffix, mg, current))
 }
 
 func (c *client) closeConn(ctx context.Context, conn *pgx.Conn) {
@@ -144,15 +382,132 @@ func WithMigrations(fsys fs.FS, act MigrateAction) ClientOptionFunc {
 	}
 }
 
+// WithMaxConns sets the maximum size of the pool. The default is the greater of 4 and the number of CPUs.
+func WithMaxConns(n int32) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.MaxConns = n })
+}
+
+// WithMinConns sets how many connections the pool keeps open even when idle. The default is 0.
+func WithMinConns(n int32) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.MinConns = n })
+}
+
+// WithMaxConnLifetime sets how long a connection is used before it is closed. The default is one hour.
+func WithMaxConnLifetime(d time.Duration) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.MaxConnLifetime = d })
+}
+
+// WithMaxConnIdleTime sets how long an idle connection is kept before it is closed. The default is 30 minutes.
+func WithMaxConnIdleTime(d time.Duration) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.MaxConnIdleTime = d })
+}
+
+// WithHealthCheckPeriod sets how often idle connections are checked. The default is one minute.
+func WithHealthCheckPeriod(d time.Duration) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.HealthCheckPeriod = d })
+}
+
+// WithQueryExecMode sets how statements are sent, see pgx.QueryExecMode. The default prepares and caches every
+// statement on the connection.
+func WithQueryExecMode(mode QueryExecMode) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.ConnConfig.DefaultQueryExecMode = mode })
+}
+
+// WithPgBouncer makes the client work behind PgBouncer in transaction pooling mode, where consecutive statements may
+// run on different server connections, so statements prepared on one are missing on the next. Statements are sent
+// with the extended protocol without preparing them and the statement caches are disabled. Session features such as
+// LISTEN and session advisory locks still require a direct connection.
+func WithPgBouncer() ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) {
+		cfg.ConnConfig.DefaultQueryExecMode = QueryExecModeExec
+		cfg.ConnConfig.StatementCacheCapacity = 0
+		cfg.ConnConfig.DescriptionCacheCapacity = 0
+	})
+}
+
+// WithPoolConfig lets fn change the pool configuration parsed from the URL before the pool is created. Options
+// override settings given as pool_* URL parameters.
+func WithPoolConfig(fn func(*pgxpool.Config)) ClientOptionFunc {
+	return func(c *client) { c.poolConfig = append(c.poolConfig, fn) }
+}
+
+// WithAfterConnect adds a hook run on every new connection before it is used, e.g. to register custom types or set
+// session variables. Hooks run in the order they were added; an error discards the connection.
+func WithAfterConnect(fn func(ctx context.Context, conn *pgx.Conn) error) ClientOptionFunc {
+	return func(c *client) { c.afterConnect = append(c.afterConnect, fn) }
+}
+
+// WithTypes registers user-defined types on every connection, see RegisterTypes.
+func WithTypes(names ...string) ClientOptionFunc {
+	return WithAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		return RegisterTypes(ctx, conn, names...)
+	})
+}
+
+// WithHstore registers the hstore type on every connection, see RegisterHstore.
+func WithHstore() ClientOptionFunc { return WithAfterConnect(RegisterHstore) }
+
+// WithVector registers the pgvector type on every connection, see RegisterVector.
+func WithVector() ClientOptionFunc { return WithAfterConnect(RegisterVector) }
+
+// WithRuntimeParam sets a run-time parameter, such as search_path or application_name, sent when connecting, which
+// unlike a SET in WithAfterConnect costs no extra round trip.
+func WithRuntimeParam(name, value string) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) { cfg.ConnConfig.RuntimeParams[name] = value })
+}
+
+// WithConnectRetry makes Open try up to attempts times to reach the database, waiting backoff after the first
+// failure and twice as long after each further one, up to 30s, with jitter. It helps services starting before their
+// database, as is common with container orchestration.
+func WithConnectRetry(attempts int, backoff time.Duration) ClientOptionFunc {
+	return func(c *client) { c.connAttempts, c.connBackoff = attempts, backoff }
+}
+
+// WithTracer adds a tracer to the connections of the pool. Several tracers may be added; batch, copy, prepare and
+// connect events reach those implementing the respective pgx interfaces.
+func WithTracer(t pgx.QueryTracer) ClientOptionFunc {
+	return func(c *client) { c.tracers = append(c.tracers, t) }
+}
+
+// WithOTel traces statements with OpenTelemetry, see OTelTracer.
+func WithOTel(opts ...OTelOption) ClientOptionFunc {
+	return WithTracer(NewOTelTracer(opts...))
+}
+
+// WithMetrics exposes the statistics of the pool and a histogram of query durations to Prometheus. The collectors are
+// registered when the client is opened.
+func WithMetrics(opts ...MetricsOption) ClientOptionFunc {
+	return func(c *client) { c.metrics = newPoolMetrics(opts) }
+}
+
+// WithQueryLog logs statements through the logger of WithLogger, or slog.Default, see QueryLogger.
+func WithQueryLog(opts ...QueryLoggerOption) ClientOptionFunc {
+	return func(c *client) {
+		c.queryLogOn = true
+		c.queryLog = append(c.queryLog, opts...)
+	}
+}
+
+// WithSlowQueryLog logs statements taking longer than threshold at warn level through the logger of WithLogger, or
+// slog.Default, independently of WithQueryLog. Entries carry the normalized statement and a fingerprint of it, so
+// occurrences of the same slow query can be grouped.
+func WithSlowQueryLog(threshold time.Duration) ClientOptionFunc {
+	return func(c *client) { c.slowQuery = threshold }
+}
+
+// WithHealthQuery makes HealthCheck also run "SELECT 1", failing if it takes longer than timeout. A zero timeout only
+// applies the deadline of the caller's context.
+func WithHealthQuery(timeout time.Duration) ClientOptionFunc {
+	return func(c *client) { c.healthQuery, c.healthTimeout = true, timeout }
+}
+
+// ParseMigrateAction parses "up", "down", "to:N", "up:N" and "down:N".
 func ParseMigrateAction(s string) (MigrateAction, error) {
-	switch strings.ToLower(s) {
-	case "up":
-		return MigrateUp, nil
-	case "down":
-		return MigrateDown, nil
-	default:
-		return "", fmt.Errorf("invalid migrate action: %s", s)
+	act := MigrateAction(strings.ToLower(s))
+	if _, err := act.target(0, math.MaxInt32); err != nil {
+		return "", err
 	}
+	return act, nil
 }
 
 type MigrateActionFlag struct {
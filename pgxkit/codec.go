@@ -0,0 +1,101 @@
+package pgxkit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RegisterTypes loads user-defined types, such as enums, composites, domains and ranges, and their array types from
+// the catalog and registers them on conn, so they can be scanned into and encoded from Go values. Types depending on
+// others, e.g. a composite with an enum field, must be listed after them.
+func RegisterTypes(ctx context.Context, conn *pgx.Conn, names ...string) error {
+	for _, name := range names {
+		for _, name := range []string{name, name + "[]"} {
+			t, err := conn.LoadType(ctx, name)
+			if err != nil {
+				return fmt.Errorf("loading type %s: %w", name, err)
+			}
+			conn.TypeMap().RegisterType(t)
+		}
+	}
+	return nil
+}
+
+// RegisterHstore registers the type of the hstore extension, which is scanned into and encoded from pgtype.Hstore or
+// map[string]*string.
+func RegisterHstore(ctx context.Context, conn *pgx.Conn) error {
+	return registerExtensionType(ctx, conn, "hstore", pgtype.HstoreCodec{})
+}
+
+// RegisterVector registers the vector type of the pgvector extension, which is scanned into and encoded from Vector.
+func RegisterVector(ctx context.Context, conn *pgx.Conn) error {
+	return registerExtensionType(ctx, conn, "vector", &pgtype.TextFormatOnlyCodec{Codec: pgtype.TextCodec{}})
+}
+
+// registerExtensionType registers a type with codec and its array type. Types of extensions have no fixed OID, so it
+// is looked up by name.
+func registerExtensionType(ctx context.Context, conn *pgx.Conn, name string, codec pgtype.Codec) error {
+	var oid, arrayOID uint32
+	err := conn.QueryRow(ctx, "SELECT oid, typarray FROM pg_type WHERE oid = $1::text::regtype", name).Scan(&oid, &arrayOID)
+	if err != nil {
+		return fmt.Errorf("loading type %s: %w", name, err)
+	}
+
+	t := &pgtype.Type{Name: name, OID: oid, Codec: codec}
+	conn.TypeMap().RegisterType(t)
+	conn.TypeMap().RegisterType(&pgtype.Type{Name: "_" + name, OID: arrayOID, Codec: &pgtype.ArrayCodec{ElementType: t}})
+
+	return nil
+}
+
+// Vector is an embedding stored in a pgvector column, see RegisterVector.
+type Vector []float32
+
+func (v Vector) TextValue() (pgtype.Text, error) {
+	if v == nil {
+		return pgtype.Text{}, nil
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+
+	return pgtype.Text{String: b.String(), Valid: true}, nil
+}
+
+func (v *Vector) ScanText(t pgtype.Text) error {
+	if !t.Valid {
+		*v = nil
+		return nil
+	}
+
+	s, ok := strings.CutPrefix(t.String, "[")
+	if s, ok = strings.CutSuffix(s, "]"); !ok {
+		return fmt.Errorf("invalid vector %q", t.String)
+	}
+
+	vec := make(Vector, 0, strings.Count(s, ",")+1)
+	if s != "" {
+		for _, part := range strings.Split(s, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+			if err != nil {
+				return fmt.Errorf("invalid vector %q: %w", t.String, err)
+			}
+			vec = append(vec, float32(f))
+		}
+	}
+	*v = vec
+
+	return nil
+}
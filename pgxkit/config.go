@@ -0,0 +1,232 @@
+package pgxkit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	_defaultHost    = "localhost"
+	_defaultPort    = 5432
+	_defaultSSLMode = "prefer"
+)
+
+// Config holds the connection settings of a database. The pool settings are optional and keep the pgxpool defaults
+// when zero.
+type Config struct {
+	Host              string
+	Port              int
+	Database          string
+	User              string
+	Password          string
+	SSLMode           string
+	ApplicationName   string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Host:    _defaultHost,
+		Port:    _defaultPort,
+		SSLMode: _defaultSSLMode,
+	}
+}
+
+// FromEnv returns DefaultConfig overridden by the libpq environment variables PGHOST, PGPORT, PGDATABASE, PGUSER,
+// PGPASSWORD, PGSSLMODE and PGAPPNAME, and the pool settings PGPOOL_MAX_CONNS, PGPOOL_MIN_CONNS,
+// PGPOOL_MAX_CONN_LIFETIME, PGPOOL_MAX_CONN_IDLE_TIME and PGPOOL_HEALTH_CHECK_PERIOD.
+func FromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	var env Config
+	var errs []error
+
+	env.Host = os.Getenv("PGHOST")
+	env.Database = os.Getenv("PGDATABASE")
+	env.User = os.Getenv("PGUSER")
+	env.Password = os.Getenv("PGPASSWORD")
+	env.SSLMode = os.Getenv("PGSSLMODE")
+	env.ApplicationName = os.Getenv("PGAPPNAME")
+
+	if v := os.Getenv("PGPORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		errs = append(errs, envErr("PGPORT", err))
+		env.Port = port
+	}
+
+	env.MaxConns, errs = envInt32("PGPOOL_MAX_CONNS", errs)
+	env.MinConns, errs = envInt32("PGPOOL_MIN_CONNS", errs)
+	env.MaxConnLifetime, errs = envDuration("PGPOOL_MAX_CONN_LIFETIME", errs)
+	env.MaxConnIdleTime, errs = envDuration("PGPOOL_MAX_CONN_IDLE_TIME", errs)
+	env.HealthCheckPeriod, errs = envDuration("PGPOOL_HEALTH_CHECK_PERIOD", errs)
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
+	cfg.Override(env)
+
+	return cfg, nil
+}
+
+func envInt32(name string, errs []error) (int32, []error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, errs
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	return int32(n), append(errs, envErr(name, err))
+}
+
+func envDuration(name string, errs []error) (time.Duration, []error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, errs
+	}
+	d, err := time.ParseDuration(v)
+	return d, append(errs, envErr(name, err))
+}
+
+func envErr(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("environment variable %s: %w", name, err)
+}
+
+func (c *Config) Override(other Config) {
+	if other.Host != "" {
+		c.Host = other.Host
+	}
+
+	if other.Port != 0 {
+		c.Port = other.Port
+	}
+
+	if other.Database != "" {
+		c.Database = other.Database
+	}
+
+	if other.User != "" {
+		c.User = other.User
+	}
+
+	if other.Password != "" {
+		c.Password = other.Password
+	}
+
+	if other.SSLMode != "" {
+		c.SSLMode = other.SSLMode
+	}
+
+	if other.ApplicationName != "" {
+		c.ApplicationName = other.ApplicationName
+	}
+
+	if other.MaxConns != 0 {
+		c.MaxConns = other.MaxConns
+	}
+
+	if other.MinConns != 0 {
+		c.MinConns = other.MinConns
+	}
+
+	if other.MaxConnLifetime != 0 {
+		c.MaxConnLifetime = other.MaxConnLifetime
+	}
+
+	if other.MaxConnIdleTime != 0 {
+		c.MaxConnIdleTime = other.MaxConnIdleTime
+	}
+
+	if other.HealthCheckPeriod != 0 {
+		c.HealthCheckPeriod = other.HealthCheckPeriod
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.Host == "" {
+		return errors.New("host must not be empty")
+	}
+
+	if c.Port <= 0 || c.Port > 65535 {
+		return errors.New("port must be between 1 and 65535")
+	}
+
+	switch c.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("invalid ssl mode: %s", c.SSLMode)
+	}
+
+	if c.MaxConns < 0 || c.MinConns < 0 {
+		return errors.New("pool sizes must not be negative")
+	}
+
+	if c.MaxConns > 0 && c.MinConns > c.MaxConns {
+		return errors.New("min conns must not exceed max conns")
+	}
+
+	return nil
+}
+
+// DSN returns the connection URL for NewClient or Open, with the pool settings as pool_* parameters.
+func (c Config) DSN() string { return c.url().String() }
+
+// Redacted is DSN with the password masked, for logging.
+func (c Config) Redacted() string { return c.url().Redacted() }
+
+func (c Config) url() *url.URL {
+	u := url.URL{Scheme: "postgres", Path: "/" + c.Database}
+	q := make(url.Values)
+
+	// A host starting with a slash is the directory of a unix socket, which a URL can only carry as a parameter.
+	if strings.HasPrefix(c.Host, "/") {
+		q.Set("host", c.Host)
+		q.Set("port", strconv.Itoa(c.Port))
+	} else {
+		u.Host = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	}
+
+	switch {
+	case c.User != "" && c.Password != "":
+		u.User = url.UserPassword(c.User, c.Password)
+	case c.User != "":
+		u.User = url.User(c.User)
+	}
+
+	if c.SSLMode != "" {
+		q.Set("sslmode", c.SSLMode)
+	}
+	if c.ApplicationName != "" {
+		q.Set("application_name", c.ApplicationName)
+	}
+	if c.MaxConns > 0 {
+		q.Set("pool_max_conns", strconv.Itoa(int(c.MaxConns)))
+	}
+	if c.MinConns > 0 {
+		q.Set("pool_min_conns", strconv.Itoa(int(c.MinConns)))
+	}
+	if c.MaxConnLifetime > 0 {
+		q.Set("pool_max_conn_lifetime", c.MaxConnLifetime.String())
+	}
+	if c.MaxConnIdleTime > 0 {
+		q.Set("pool_max_conn_idle_time", c.MaxConnIdleTime.String())
+	}
+	if c.HealthCheckPeriod > 0 {
+		q.Set("pool_health_check_period", c.HealthCheckPeriod.String())
+	}
+	u.RawQuery = q.Encode()
+
+	return &u
+}
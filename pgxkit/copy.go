@@ -0,0 +1,69 @@
+package pgxkit
+
+import (
+	"context"
+	"iter"
+	"reflect"
+)
+
+// StructSource is a pgx.CopyFromSource reading the fields of structs, mapped to columns like for InsertRows, so
+// large ingests stream into CopyFrom without building [][]any by hand. Columns returns the column names to copy.
+type StructSource[T any] struct {
+	fields []structField
+	next   func() (T, bool)
+	stop   func()
+	cur    T
+}
+
+// NewStructSource reads rows.
+func NewStructSource[T any](rows []T) *StructSource[T] {
+	i := 0
+	return &StructSource[T]{
+		fields: insertFields[T](),
+		next: func() (T, bool) {
+			if i >= len(rows) {
+				var zero T
+				return zero, false
+			}
+			i++
+			return rows[i-1], true
+		},
+		stop: func() {},
+	}
+}
+
+// NewSeqSource reads the values of seq as they are produced. Close must be called if copying ends before seq is
+// exhausted, e.g. on an error; CopySeq takes care of that.
+func NewSeqSource[T any](seq iter.Seq[T]) *StructSource[T] {
+	next, stop := iter.Pull(seq)
+	return &StructSource[T]{fields: insertFields[T](), next: next, stop: stop}
+}
+
+func (s *StructSource[T]) Columns() []string { return columnNames(s.fields) }
+
+func (s *StructSource[T]) Next() bool {
+	var ok bool
+	s.cur, ok = s.next()
+	if !ok {
+		s.stop()
+	}
+	return ok
+}
+
+func (s *StructSource[T]) Values() ([]any, error) {
+	return fieldValues(reflect.ValueOf(s.cur), s.fields)
+}
+
+func (s *StructSource[T]) Err() error { return nil }
+
+// Close stops reading the underlying sequence.
+func (s *StructSource[T]) Close() { s.stop() }
+
+// CopySeq copies the values of seq into table with COPY while seq produces them.
+func CopySeq[T any](ctx context.Context, db Copier, table string, seq iter.Seq[T]) (int64, error) {
+	src := NewSeqSource(seq)
+	defer src.Close()
+
+	n, err := db.CopyFrom(ctx, tableIdentifier(table), src.Columns(), src)
+	return n, mapErr(err)
+}
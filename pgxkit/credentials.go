@@ -0,0 +1,89 @@
+package pgxkit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Credentials authenticate new connections. Expiry, if set, is when the password stops being accepted for new
+// connections; established connections are not affected.
+type Credentials struct {
+	User     string
+	Password string
+	Expiry   time.Time
+}
+
+// CredentialProvider fetches the credentials for new connections, e.g. a short-lived AWS RDS IAM or GCP Cloud SQL
+// token, or a dynamic Vault secret, so pools keep working as credentials rotate.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) { return f(ctx) }
+
+type cachedCredentials struct {
+	provider      CredentialProvider
+	refreshBefore time.Duration
+
+	mu    sync.Mutex
+	creds Credentials
+	ok    bool
+}
+
+// CachedCredentials reuses the credentials of p until refreshBefore their expiry, so a burst of new connections does
+// not fetch a token each. Credentials without expiry are fetched every time.
+func CachedCredentials(p CredentialProvider, refreshBefore time.Duration) CredentialProvider {
+	return &cachedCredentials{provider: p, refreshBefore: refreshBefore}
+}
+
+func (c *cachedCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ok && time.Until(c.creds.Expiry) > c.refreshBefore {
+		return c.creds, nil
+	}
+
+	creds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	c.creds, c.ok = creds, !creds.Expiry.IsZero()
+
+	return creds, nil
+}
+
+// WithCredentials authenticates every new connection with the credentials of p, overriding those of the URL. An
+// empty User keeps the user of the URL.
+func WithCredentials(p CredentialProvider) ClientOptionFunc {
+	return WithPoolConfig(func(cfg *pgxpool.Config) {
+		configured := cfg.BeforeConnect
+		cfg.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			if configured != nil {
+				if err := configured(ctx, cc); err != nil {
+					return err
+				}
+			}
+
+			creds, err := p.Credentials(ctx)
+			if err != nil {
+				return fmt.Errorf("fetching credentials: %w", err)
+			}
+
+			if creds.User != "" {
+				cc.User = creds.User
+			}
+			cc.Password = creds.Password
+
+			return nil
+		}
+	})
+}
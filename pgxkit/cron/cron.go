@@ -0,0 +1,407 @@
+// Package cron runs scheduled jobs across replicas. Schedules are stored in Postgres and every firing is claimed
+// under an advisory lock, so each job fires on exactly one replica no matter how many run the scheduler. Runs are
+// recorded in a history table.
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+const (
+	_defaultTickInterval = time.Second
+	_defaultMisfireGrace = time.Minute
+	_lockPrefix          = "pgxkit_cron:"
+)
+
+// Schema creates the schedule and run history tables if they do not exist.
+const Schema = `CREATE TABLE IF NOT EXISTS pgxkit_cron_jobs (
+	name        TEXT PRIMARY KEY,
+	schedule    TEXT NOT NULL,
+	next_run_at TIMESTAMPTZ NOT NULL,
+	last_run_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS pgxkit_cron_runs (
+	id           BIGSERIAL PRIMARY KEY,
+	name         TEXT NOT NULL,
+	scheduled_at TIMESTAMPTZ NOT NULL,
+	started_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	finished_at  TIMESTAMPTZ,
+	status       TEXT NOT NULL CHECK (status IN ('running', 'succeeded', 'failed', 'skipped')),
+	error        TEXT,
+	runner       TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pgxkit_cron_runs_name_idx ON pgxkit_cron_runs (name, id DESC);`
+
+// MisfirePolicy decides what happens when a job is noticed more than the misfire grace period after it was due,
+// e.g. because no replica was running.
+type MisfirePolicy int
+
+const (
+	// MisfireRunOnce runs the job once, however many activations were missed.
+	MisfireRunOnce MisfirePolicy = iota
+	// MisfireSkip records the missed activation as skipped and waits for the next one.
+	MisfireSkip
+)
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunSkipped   RunStatus = "skipped"
+)
+
+// Run is an entry of the run history.
+type Run struct {
+	ID          int64      `db:"id"`
+	Name        string     `db:"name"`
+	ScheduledAt time.Time  `db:"scheduled_at"`
+	StartedAt   time.Time  `db:"started_at"`
+	FinishedAt  *time.Time `db:"finished_at"`
+	Status      RunStatus  `db:"status"`
+	Error       *string    `db:"error"`
+	Runner      string     `db:"runner"`
+}
+
+type Func func(ctx context.Context) error
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       Func
+	jitter   time.Duration
+	timeout  time.Duration
+	misfire  MisfirePolicy
+	grace    time.Duration
+	running  bool
+}
+
+// Scheduler fires registered jobs according to their schedules.
+type Scheduler struct {
+	db    pgxkit.DB
+	locks pgxkit.Acquirer
+	id    string
+	tick  time.Duration
+	loc   *time.Location
+	log   *slog.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*job
+	wg   sync.WaitGroup
+}
+
+func NewScheduler(db pgxkit.DB, opts ...SchedulerOption) *Scheduler {
+	host, _ := os.Hostname()
+
+	s := Scheduler{
+		db:    db,
+		locks: db,
+		id:    host + ":" + strconv.Itoa(os.Getpid()),
+		tick:  _defaultTickInterval,
+		loc:   time.UTC,
+		jobs:  make(map[string]*job),
+	}
+
+	for _, opt := range opts {
+		opt.applyToScheduler(&s)
+	}
+
+	return &s
+}
+
+// Register adds a job firing fn according to the cron spec, see Parse. Jobs must be registered before Run.
+func (s *Scheduler) Register(name, spec string, fn Func, opts ...JobOption) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now().In(s.loc)).IsZero() {
+		return fmt.Errorf("cron spec %q of job %q never fires", spec, name)
+	}
+
+	j := job{name: name, spec: spec, schedule: schedule, fn: fn, grace: _defaultMisfireGrace}
+	for _, opt := range opts {
+		opt.applyToJob(&j)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("cron job %q already registered", name)
+	}
+	s.jobs[name] = &j
+
+	return nil
+}
+
+// Run stores the schedules and fires due jobs until ctx is done, then waits for running jobs, whose contexts are
+// canceled, to return. A changed spec resets the next activation of a job.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if err := s.store(ctx); err != nil {
+		return err
+	}
+
+	t := time.NewTicker(s.tick)
+	defer t.Stop()
+
+	for {
+		if err := s.fireDue(ctx); err != nil && ctx.Err() == nil && s.log != nil {
+			s.log.ErrorContext(ctx, "firing cron jobs", slog.Group("error", slog.String("msg", err.Error())))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Scheduler) store(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().In(s.loc)
+	for _, j := range s.jobs {
+		if err := pgxkit.Exec(ctx, s.db, `INSERT INTO pgxkit_cron_jobs (name, schedule, next_run_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE SET
+	schedule = excluded.schedule,
+	next_run_at = CASE WHEN pgxkit_cron_jobs.schedule = excluded.schedule
+		THEN pgxkit_cron_jobs.next_run_at ELSE excluded.next_run_at END`, j.name, j.spec, j.next(now)); err != nil {
+			return fmt.Errorf("storing cron job %q: %w", j.name, err)
+		}
+	}
+
+	return nil
+}
+
+// fireDue starts every due job that is not already running on this replica.
+func (s *Scheduler) fireDue(ctx context.Context) error {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.jobs))
+	for name, j := range s.jobs {
+		if !j.running {
+			names = append(names, name)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	due, err := pgxkit.Query[struct {
+		Name string `db:"name"`
+	}](ctx, s.db, "SELECT name FROM pgxkit_cron_jobs WHERE next_run_at <= now() AND name = ANY($1)", names)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range due {
+		s.mu.Lock()
+		j := s.jobs[d.Name]
+		if j.running {
+			s.mu.Unlock()
+			continue
+		}
+		j.running = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				j.running = false
+				s.mu.Unlock()
+			}()
+
+			if err := s.fire(ctx, j); err != nil && ctx.Err() == nil && s.log != nil {
+				s.log.ErrorContext(ctx, "firing cron job",
+					slog.String("job", j.name),
+					slog.Group("error", slog.String("msg", err.Error())),
+				)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// fire claims the due activation of j under its advisory lock, advances the schedule and runs j. The lock is held
+// while j runs, so runs never overlap across replicas. Holding it keeps a connection of the lock pool checked out for
+// the whole run, see WithLockPool.
+func (s *Scheduler) fire(ctx context.Context, j *job) error {
+	lock, err := pgxkit.TryAdvisoryLock(ctx, s.locks, pgxkit.AdvisoryLockKey(_lockPrefix+j.name))
+	if errors.Is(err, pgxkit.ErrLockNotAcquired) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Release(ctx)
+
+	conn := lock.Conn()
+
+	// Another replica may have fired the activation between the check and taking the lock.
+	var scheduled time.Time
+	if err := conn.QueryRow(ctx, "SELECT next_run_at FROM pgxkit_cron_jobs WHERE name = $1", j.name).Scan(&scheduled); err != nil {
+		return fmt.Errorf("reading schedule: %w", err)
+	}
+
+	now := time.Now().In(s.loc)
+	if scheduled.After(now) {
+		return nil
+	}
+
+	if _, err := conn.Exec(ctx, "UPDATE pgxkit_cron_jobs SET next_run_at = $2, last_run_at = $3 WHERE name = $1",
+		j.name, j.next(now), now); err != nil {
+		return fmt.Errorf("advancing schedule: %w", err)
+	}
+
+	if j.misfire == MisfireSkip && now.Sub(scheduled) > j.grace {
+		_, err := conn.Exec(ctx, `INSERT INTO pgxkit_cron_runs (name, scheduled_at, finished_at, status, error, runner)
+VALUES ($1, $2, now(), 'skipped', 'misfired', $3)`, j.name, scheduled, s.id)
+		return err
+	}
+
+	var runID int64
+	if err := conn.QueryRow(ctx, `INSERT INTO pgxkit_cron_runs (name, scheduled_at, status, runner)
+VALUES ($1, $2, 'running', $3)
+RETURNING id`, j.name, scheduled, s.id).Scan(&runID); err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+
+	runErr := j.run(ctx)
+
+	status, msg := RunSucceeded, (*string)(nil)
+	if runErr != nil {
+		status = RunFailed
+		m := runErr.Error()
+		msg = &m
+
+		if s.log != nil {
+			s.log.ErrorContext(ctx, "cron job failed",
+				slog.String("job", j.name),
+				slog.Group("error", slog.String("msg", runErr.Error())),
+			)
+		}
+	}
+
+	if _, err := conn.Exec(context.WithoutCancel(ctx), `UPDATE pgxkit_cron_runs SET finished_at = now(), status = $2, error = $3
+WHERE id = $1`, runID, status, msg); err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+
+	return nil
+}
+
+// next returns the activation after now, delayed by a random share of the jitter.
+func (j *job) next(now time.Time) time.Time {
+	next := j.schedule.Next(now)
+	if j.jitter > 0 {
+		next = next.Add(rand.N(j.jitter))
+	}
+	return next
+}
+
+func (j *job) run(ctx context.Context) (err error) {
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+
+	return j.fn(ctx)
+}
+
+// History returns the latest runs of the job name, newest first.
+func History(ctx context.Context, db pgxkit.Queryer, name string, limit int) ([]Run, error) {
+	return pgxkit.Query[Run](ctx, db, `SELECT id, name, scheduled_at, started_at, finished_at, status, error, runner
+FROM pgxkit_cron_runs
+WHERE name = $1
+ORDER BY id DESC
+LIMIT $2`, name, limit)
+}
+
+type SchedulerOption interface {
+	applyToScheduler(*Scheduler)
+}
+
+type SchedulerOptionFunc func(*Scheduler)
+
+func (f SchedulerOptionFunc) applyToScheduler(s *Scheduler) { f(s) }
+
+// WithLocation sets the time zone schedules are evaluated in. The default is UTC.
+func WithLocation(loc *time.Location) SchedulerOptionFunc {
+	return func(s *Scheduler) { s.loc = loc }
+}
+
+// WithTickInterval sets how often due jobs are looked for. The default is one second.
+func WithTickInterval(d time.Duration) SchedulerOptionFunc {
+	return func(s *Scheduler) { s.tick = d }
+}
+
+// WithLockPool sets the pool the advisory locks of running jobs are taken on, which is the scheduler's database by
+// default. Every running job keeps one connection of it checked out until it returns, so jobs that are slow or many
+// can exhaust a pool they also need for their own queries; a small dedicated pool, sized for the jobs that may run at
+// the same time, avoids that.
+func WithLockPool(db pgxkit.Acquirer) SchedulerOptionFunc {
+	return func(s *Scheduler) { s.locks = db }
+}
+
+// WithRunnerID sets the identifier recorded with runs, the host name and process ID by default.
+func WithRunnerID(id string) SchedulerOptionFunc {
+	return func(s *Scheduler) { s.id = id }
+}
+
+func WithLogger(log *slog.Logger) SchedulerOptionFunc {
+	return func(s *Scheduler) { s.log = log }
+}
+
+type JobOption interface {
+	applyToJob(*job)
+}
+
+type JobOptionFunc func(*job)
+
+func (f JobOptionFunc) applyToJob(j *job) { f(j) }
+
+// WithJitter delays every activation by a random duration up to d, spreading jobs sharing a schedule.
+func WithJitter(d time.Duration) JobOptionFunc {
+	return func(j *job) { j.jitter = d }
+}
+
+// WithTimeout cancels the context of a run after d.
+func WithTimeout(d time.Duration) JobOptionFunc {
+	return func(j *job) { j.timeout = d }
+}
+
+// WithMisfire sets the misfire policy and how late an activation may be noticed before it counts as misfired. The
+// default is MisfireRunOnce with a grace period of one minute.
+func WithMisfire(policy MisfirePolicy, grace time.Duration) JobOptionFunc {
+	return func(j *job) { j.misfire, j.grace = policy, grace }
+}
@@ -0,0 +1,92 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	load := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Skipf("loading time zone %s: %v", name, err)
+		}
+		return loc
+	}
+
+	kolkata := load("Asia/Kolkata")
+	kathmandu := load("Asia/Kathmandu")
+	newYork := load("America/New_York")
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "utc",
+			spec: "0 9 * * *",
+			from: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "half hour offset",
+			spec: "0 9 * * *",
+			from: time.Date(2026, 3, 1, 7, 12, 30, 0, kolkata),
+			want: time.Date(2026, 3, 1, 9, 0, 0, 0, kolkata),
+		},
+		{
+			name: "quarter hour offset",
+			spec: "30 14 * * 1",
+			from: time.Date(2026, 3, 1, 0, 0, 0, 0, kathmandu),
+			want: time.Date(2026, 3, 2, 14, 30, 0, 0, kathmandu),
+		},
+		{
+			name: "minute step in half hour offset",
+			spec: "*/20 * * * *",
+			from: time.Date(2026, 3, 1, 9, 1, 0, 0, kolkata),
+			want: time.Date(2026, 3, 1, 9, 20, 0, 0, kolkata),
+		},
+		{
+			name: "spring forward",
+			spec: "30 2 * * *",
+			from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+		},
+		{
+			name: "fall back",
+			spec: "0 2 * * *",
+			from: time.Date(2026, 11, 1, 1, 30, 0, 0, newYork).Add(time.Hour),
+			want: time.Date(2026, 11, 1, 2, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+
+			if got := sched.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextRepeatedHour(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("loading time zone: %v", err)
+	}
+
+	// 01:10 EST, the second time the wall clock shows 01:10 on that day.
+	from := time.Date(2026, 11, 1, 6, 10, 0, 0, time.UTC).In(loc)
+
+	got := MustParse("* * * * *").Next(from)
+	if want := from.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package pgxkit
+
+import (
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ConstraintError is returned for violated constraints. It wraps the sentinel of the kind of violation, e.g.
+// ErrAlreadyExists, and the *pgconn.PgError, and tells which constraint, and where available which column, caused it,
+// so APIs can report the conflicting field.
+type ConstraintError struct {
+	Err        error
+	Constraint string
+	Table      string
+	Column     string
+	Detail     string
+
+	pgerr *pgconn.PgError
+}
+
+func newConstraintError(sentinel error, pgerr *pgconn.PgError) *ConstraintError {
+	return &ConstraintError{
+		Err:        sentinel,
+		Constraint: pgerr.ConstraintName,
+		Table:      pgerr.TableName,
+		Column:     pgerr.ColumnName,
+		Detail:     pgerr.Detail,
+		pgerr:      pgerr,
+	}
+}
+
+func (e *ConstraintError) Error() string {
+	msg := e.Err.Error()
+	if e.Constraint != "" {
+		msg += ": constraint " + e.Constraint
+	}
+	if e.Column != "" {
+		msg += " on column " + e.Column
+	}
+	if e.Table != "" {
+		msg += " of table " + e.Table
+	}
+	return msg
+}
+
+func (e *ConstraintError) Unwrap() []error { return []error{e.Err, e.pgerr} }
@@ -0,0 +1,94 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestMapErr(t *testing.T) {
+	other := errors.New("other")
+
+	tests := []struct {
+		name string
+		err  error
+		want []error
+		// pgerr tells whether the *pgconn.PgError stays in the chain, so callers can still inspect it.
+		pgerr bool
+	}{
+		{name: "nil", err: nil, want: nil},
+		{name: "no rows", err: pgx.ErrNoRows, want: []error{ErrNotFound}},
+		{name: "wrapped no rows", err: fmt.Errorf("scan: %w", pgx.ErrNoRows), want: []error{ErrNotFound}},
+		{name: "no data", err: &pgconn.PgError{Code: pgerrcode.NoData}, want: []error{ErrNotFound}},
+		{name: "no data found", err: &pgconn.PgError{Code: pgerrcode.NoDataFound}, want: []error{ErrNotFound}},
+		{name: "unique violation", err: &pgconn.PgError{Code: pgerrcode.UniqueViolation}, want: []error{ErrAlreadyExists}, pgerr: true},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}, want: []error{ErrForeignKeyViolation}, pgerr: true},
+		{name: "check violation", err: &pgconn.PgError{Code: pgerrcode.CheckViolation}, want: []error{ErrCheckViolation}, pgerr: true},
+		{name: "not null violation", err: &pgconn.PgError{Code: pgerrcode.NotNullViolation}, want: []error{ErrNotNullViolation}, pgerr: true},
+		{name: "query canceled", err: &pgconn.PgError{Code: pgerrcode.QueryCanceled}, want: []error{ErrTimeout}, pgerr: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: pgerrcode.DeadlockDetected}, want: []error{ErrDeadlock}, pgerr: true},
+		{name: "serialization failure", err: &pgconn.PgError{Code: pgerrcode.SerializationFailure}, want: []error{ErrSerializationFailure}, pgerr: true},
+		{name: "wrapped pg error", err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgerrcode.UniqueViolation}), want: []error{ErrAlreadyExists}, pgerr: true},
+		{name: "unmapped pg error", err: &pgconn.PgError{Code: pgerrcode.SyntaxError}, want: nil, pgerr: true},
+		{name: "context canceled", err: context.Canceled, want: []error{ErrCanceled, context.Canceled}},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: []error{ErrTimeout, context.DeadlineExceeded}},
+		{name: "already mapped", err: fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded), want: []error{ErrTimeout}},
+		{name: "other", err: other, want: []error{other}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapErr(tt.err)
+
+			if tt.err == nil {
+				if got != nil {
+					t.Fatalf("mapErr(nil) = %v, want nil", got)
+				}
+				return
+			}
+
+			for _, want := range tt.want {
+				if !errors.Is(got, want) {
+					t.Errorf("mapErr(%v) = %v, want it to wrap %v", tt.err, got, want)
+				}
+			}
+
+			var pgerr *pgconn.PgError
+			if errors.As(got, &pgerr) != tt.pgerr {
+				t.Errorf("mapErr(%v) = %v, wraps *pgconn.PgError: %t, want %t", tt.err, got, !tt.pgerr, tt.pgerr)
+			}
+		})
+	}
+}
+
+func TestMapErrConstraint(t *testing.T) {
+	pgerr := &pgconn.PgError{
+		Code:           pgerrcode.UniqueViolation,
+		ConstraintName: "users_email_key",
+		TableName:      "users",
+		ColumnName:     "email",
+		Detail:         "Key (email)=(a@b.c) already exists.",
+	}
+
+	err := mapErr(pgerr)
+
+	var cerr *ConstraintError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("mapErr() = %T, want *ConstraintError", err)
+	}
+
+	if cerr.Constraint != pgerr.ConstraintName || cerr.Table != pgerr.TableName ||
+		cerr.Column != pgerr.ColumnName || cerr.Detail != pgerr.Detail {
+		t.Errorf("ConstraintError = %+v, want fields of %+v", cerr, pgerr)
+	}
+
+	want := "already exists: constraint users_email_key on column email of table users"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// _maxParams is the limit of bind parameters of a statement in the Postgres protocol.
+const _maxParams = 65535
+
+// InsertRows copies rows into table with COPY, which is the fastest way to insert many rows. The columns are those
+// of T's fields, named by their db tags or else the snake-cased field names; fields tagged `db:",generated"` are left
+// to the database.
+func InsertRows[T any](ctx context.Context, db Copier, table string, rows []T) (int64, error) {
+	src := NewStructSource(rows)
+
+	n, err := db.CopyFrom(ctx, tableIdentifier(table), src.Columns(), src)
+	return n, mapErr(err)
+}
+
+// InsertRowsReturning inserts rows with multi-row INSERT statements, split to stay within the parameter limit, and
+// returns the given columns of the inserted rows, e.g. generated IDs. With a single returning column R is the type
+// of that column, otherwise a struct scanned by name.
+func InsertRowsReturning[T, R any](ctx context.Context, db Queryer, table string, rows []T, returning ...string) ([]R, error) {
+	if len(returning) == 0 {
+		return nil, errors.New("InsertRowsReturning requires returning columns, use InsertRows otherwise")
+	}
+
+	fields := insertFields[T]()
+	if len(fields) == 0 || len(rows) == 0 {
+		return nil, nil
+	}
+
+	scan := pgx.RowToStructByName[R]
+	if len(returning) == 1 {
+		scan = pgx.RowTo[R]
+	}
+
+	prefix := "INSERT INTO " + tableIdentifier(table).Sanitize() + " (" + quoteColumns(columnNames(fields)) + ") VALUES "
+	suffix := " RETURNING " + quoteColumns(returning)
+
+	chunk := _maxParams / len(fields)
+	results := make([]R, 0, len(rows))
+
+	for start := 0; start < len(rows); start += chunk {
+		end := min(start+chunk, len(rows))
+
+		sql, args, err := valuesSQL(rows[start:end], fields)
+		if err != nil {
+			return results, err
+		}
+
+		qrows, _ := db.Query(ctx, prefix+sql+suffix, args...)
+		res, err := pgx.CollectRows(qrows, scan)
+		if err != nil {
+			return results, mapErr(err)
+		}
+		results = append(results, res...)
+	}
+
+	return results, nil
+}
+
+// valuesSQL returns the VALUES tuples of rows with numbered placeholders and the matching arguments.
+func valuesSQL[T any](rows []T, fields []structField) (string, []any, error) {
+	var b strings.Builder
+	args := make([]any, 0, len(rows)*len(fields))
+
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		values, err := fieldValues(reflect.ValueOf(row), fields)
+		if err != nil {
+			return "", nil, fmt.Errorf("row %d: %w", i, err)
+		}
+
+		b.WriteByte('(')
+		for j, v := range values {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			args = append(args, v)
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(len(args)))
+		}
+		b.WriteByte(')')
+	}
+
+	return b.String(), args, nil
+}
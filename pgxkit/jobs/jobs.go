@@ -0,0 +1,150 @@
+// Package jobs is a job queue stored in Postgres. Jobs are enqueued with Enqueue, possibly within the transaction of
+// the change they follow from, and processed by a Worker, which claims them with FOR UPDATE SKIP LOCKED so any number
+// of workers can share a queue. Failed jobs are retried with backoff until they run out of attempts and are marked
+// dead.
+package jobs
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+const (
+	_defaultQueue       = "default"
+	_defaultMaxAttempts = 25
+)
+
+//go:embed migrations/*.sql
+var _migrations embed.FS
+
+// Migrations holds the tern migration creating the jobs table. It is versioned in its own table, so it is applied with
+// pgxkit.Migrator.Migrate independently of the migrations of the application. Applications may instead copy it into
+// their own migrations or execute Schema.
+var Migrations = pgxkit.VersionedMigrations(_migrations, "public.pgxkit_jobs_schema_version")
+
+//go:embed migrations/001_create_pgxkit_jobs.sql
+var _createMigration string
+
+// Schema creates the jobs table if it does not exist. It is the up part of the migration in Migrations.
+var Schema = upMigration(_createMigration)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusDead    Status = "dead"
+)
+
+type Job struct {
+	ID          int64           `db:"id"`
+	Queue       string          `db:"queue"`
+	Kind        string          `db:"kind"`
+	Payload     json.RawMessage `db:"payload"`
+	Priority    int16           `db:"priority"`
+	Status      Status          `db:"status"`
+	Attempts    int32           `db:"attempts"`
+	MaxAttempts int32           `db:"max_attempts"`
+	RunAt       time.Time       `db:"run_at"`
+	LastError   *string         `db:"last_error"`
+	CreatedAt   time.Time       `db:"created_at"`
+}
+
+// Decode unmarshals the payload of the job into v.
+func (j *Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("decoding payload of job %d: %w", j.ID, err)
+	}
+	return nil
+}
+
+// upMigration returns the part of a tern migration above the "---- create above / drop below ----" line.
+func upMigration(sql string) string {
+	up, _, _ := strings.Cut(sql, "---- create above / drop below ----")
+	return strings.TrimSpace(up)
+}
+
+const _jobColumns = "id, queue, kind, payload, priority, status, attempts, max_attempts, run_at, last_error, created_at"
+
+type enqueueConfig struct {
+	queue       string
+	priority    int16
+	runAt       time.Time
+	maxAttempts int32
+}
+
+// Enqueue adds a job of kind with payload encoded as JSON and returns its ID. Passing a transaction as db makes the
+// job visible to workers only once the transaction commits.
+func Enqueue(ctx context.Context, db pgxkit.Queryer, kind string, payload any, opts ...EnqueueOption) (int64, error) {
+	cfg := enqueueConfig{queue: _defaultQueue, maxAttempts: _defaultMaxAttempts}
+	for _, opt := range opts {
+		opt.applyToEnqueue(&cfg)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("encoding payload: %w", err)
+	}
+
+	var runAt *time.Time
+	if !cfg.runAt.IsZero() {
+		runAt = &cfg.runAt
+	}
+
+	return pgxkit.QueryValue[int64](ctx, db, `INSERT INTO pgxkit_jobs (queue, kind, payload, priority, max_attempts, run_at)
+VALUES ($1, $2, $3, $4, $5, COALESCE($6, now()))
+RETURNING id`, cfg.queue, kind, b, cfg.priority, cfg.maxAttempts, runAt)
+}
+
+// Get returns the job with id, or pgxkit.ErrNotFound.
+func Get(ctx context.Context, db pgxkit.Queryer, id int64) (Job, error) {
+	return pgxkit.QueryRow[Job](ctx, db, "SELECT "+_jobColumns+" FROM pgxkit_jobs WHERE id = $1", id)
+}
+
+// Requeue makes a dead job pending again with a fresh set of attempts. It returns pgxkit.ErrNotFound if there is no
+// dead job with id.
+func Requeue(ctx context.Context, db pgxkit.Execer, id int64) error {
+	return pgxkit.ExecOne(ctx, db, `UPDATE pgxkit_jobs
+SET status = 'pending', attempts = 0, run_at = now(), finished_at = NULL
+WHERE id = $1 AND status = 'dead'`, id)
+}
+
+// Prune deletes jobs that finished successfully before the given time.
+func Prune(ctx context.Context, db pgxkit.Execer, before time.Time) error {
+	return pgxkit.Exec(ctx, db, "DELETE FROM pgxkit_jobs WHERE status = 'done' AND finished_at < $1", before)
+}
+
+type EnqueueOption interface {
+	applyToEnqueue(*enqueueConfig)
+}
+
+type EnqueueOptionFunc func(*enqueueConfig)
+
+func (f EnqueueOptionFunc) applyToEnqueue(c *enqueueConfig) { f(c) }
+
+// WithQueue puts the job on a named queue, "default" unless set.
+func WithQueue(name string) EnqueueOptionFunc {
+	return func(c *enqueueConfig) { c.queue = name }
+}
+
+// WithPriority sets the priority of the job. Jobs with higher priority are claimed first.
+func WithPriority(p int16) EnqueueOptionFunc {
+	return func(c *enqueueConfig) { c.priority = p }
+}
+
+// WithRunAt delays the job until t.
+func WithRunAt(t time.Time) EnqueueOptionFunc {
+	return func(c *enqueueConfig) { c.runAt = t }
+}
+
+// WithMaxAttempts sets how often the job is run before it is marked dead. The default is 25.
+func WithMaxAttempts(n int32) EnqueueOptionFunc {
+	return func(c *enqueueConfig) { c.maxAttempts = max(n, 1) }
+}
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS pgxkit_jobs (
+	id           BIGSERIAL PRIMARY KEY,
+	queue        TEXT NOT NULL DEFAULT 'default',
+	kind         TEXT NOT NULL,
+	payload      JSONB NOT NULL DEFAULT '{}',
+	priority     SMALLINT NOT NULL DEFAULT 0,
+	status       TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'dead')),
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 25,
+	run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+	locked_at    TIMESTAMPTZ,
+	locked_by    TEXT,
+	last_error   TEXT,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	finished_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS pgxkit_jobs_fetch_idx ON pgxkit_jobs (queue, priority DESC, run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS pgxkit_jobs_running_idx ON pgxkit_jobs (locked_at) WHERE status = 'running';
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS pgxkit_jobs;
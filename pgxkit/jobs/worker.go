@@ -0,0 +1,318 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+const (
+	_defaultConcurrency  = 1
+	_defaultPollInterval = time.Second
+	_defaultLockTimeout  = 30 * time.Minute
+	_maxBackoff          = time.Hour
+)
+
+// Handler processes a job. A returned error, or a panic, fails the attempt.
+type Handler func(ctx context.Context, job *Job) error
+
+type workerDB interface {
+	pgxkit.Queryer
+	pgxkit.Execer
+}
+
+// Worker claims jobs of the kinds it has handlers for from a queue and runs them.
+type Worker struct {
+	db           workerDB
+	id           string
+	queue        string
+	concurrency  int
+	pollInterval time.Duration
+	lockTimeout  time.Duration
+	backoff      func(attempt int) time.Duration
+	log          *slog.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewWorker(db workerDB, opts ...WorkerOption) *Worker {
+	host, _ := os.Hostname()
+
+	w := Worker{
+		db:           db,
+		id:           host + ":" + strconv.Itoa(os.Getpid()),
+		queue:        _defaultQueue,
+		concurrency:  _defaultConcurrency,
+		pollInterval: _defaultPollInterval,
+		lockTimeout:  _defaultLockTimeout,
+		backoff:      DefaultBackoff,
+		handlers:     make(map[string]Handler),
+	}
+
+	for _, opt := range opts {
+		opt.applyToWorker(&w)
+	}
+
+	return &w
+}
+
+// Handle registers h for jobs of kind. Only kinds with a handler are claimed.
+func (w *Worker) Handle(kind string, h Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[kind] = h
+}
+
+func (w *Worker) kinds() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+func (w *Worker) handler(kind string) Handler {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.handlers[kind]
+}
+
+// Run processes jobs with the configured concurrency until ctx is done, then waits for running jobs to return. Jobs
+// whose worker died while running them are made pending again once their lock timed out.
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	for range w.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.rescueLoop(ctx)
+	}()
+
+	wg.Wait()
+	return nil
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.claim(ctx)
+		switch {
+		case err == nil:
+			w.process(ctx, job)
+			continue
+		case errors.Is(err, pgxkit.ErrNotFound):
+		case ctx.Err() == nil:
+			w.logError(ctx, "claiming job", err)
+		}
+
+		t := time.NewTimer(w.pollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// claim marks the next due job of a handled kind as running, skipping jobs claimed concurrently by other workers.
+func (w *Worker) claim(ctx context.Context) (*Job, error) {
+	kinds := w.kinds()
+	if len(kinds) == 0 {
+		return nil, pgxkit.ErrNotFound
+	}
+
+	job, err := pgxkit.QueryRow[Job](ctx, w.db, `UPDATE pgxkit_jobs
+SET status = 'running', attempts = attempts + 1, locked_at = now(), locked_by = $3
+WHERE id = (
+	SELECT id FROM pgxkit_jobs
+	WHERE status = 'pending' AND queue = $1 AND kind = ANY($2) AND run_at <= now()
+	ORDER BY priority DESC, run_at, id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING `+_jobColumns, w.queue, kinds, w.id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (w *Worker) process(ctx context.Context, job *Job) {
+	err := w.run(ctx, job)
+
+	// The outcome is recorded even when shutting down, so the job does not wait for the lock timeout.
+	ctx = context.WithoutCancel(ctx)
+
+	if err == nil {
+		w.finish(ctx, job, "completing job", `UPDATE pgxkit_jobs
+SET status = 'done', locked_at = NULL, locked_by = NULL, finished_at = now()
+WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3`)
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		if w.log != nil {
+			w.log.ErrorContext(ctx, "job is dead",
+				slog.Int64("job", job.ID),
+				slog.String("kind", job.Kind),
+				slog.Int("attempts", int(job.Attempts)),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+		w.finish(ctx, job, "failing job", `UPDATE pgxkit_jobs
+SET status = 'dead', last_error = $4, locked_at = NULL, locked_by = NULL, finished_at = now()
+WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3`, err.Error())
+		return
+	}
+
+	delay := w.backoff(int(job.Attempts))
+	if w.log != nil {
+		w.log.WarnContext(ctx, "job failed",
+			slog.Int64("job", job.ID),
+			slog.String("kind", job.Kind),
+			slog.Int("attempt", int(job.Attempts)),
+			slog.Duration("retry_in", delay),
+			slog.Group("error", slog.String("msg", err.Error())),
+		)
+	}
+	w.finish(ctx, job, "failing job", `UPDATE pgxkit_jobs
+SET status = 'pending', last_error = $4, run_at = now() + $5::interval, locked_at = NULL, locked_by = NULL
+WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3`, err.Error(), delay)
+}
+
+// finish records the outcome of job with sql, which is given the ID of the job, the worker ID and the attempt as $1
+// to $3 followed by args. It only updates the job while the attempt still holds it: once its lock timed out the job
+// may have been rescued and claimed again, and the outcome of the new attempt must not be overwritten.
+func (w *Worker) finish(ctx context.Context, job *Job, msg, sql string, args ...any) {
+	err := pgxkit.ExecOne(ctx, w.db, sql, append([]any{job.ID, w.id, job.Attempts}, args...)...)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgxkit.ErrNotFound):
+		if w.log != nil {
+			w.log.WarnContext(ctx, "job lock lost, outcome discarded",
+				slog.Int64("job", job.ID),
+				slog.String("kind", job.Kind),
+				slog.Int("attempt", int(job.Attempts)),
+			)
+		}
+	default:
+		w.logError(ctx, msg, err, slog.Int64("job", job.ID))
+	}
+}
+
+func (w *Worker) run(ctx context.Context, job *Job) (err error) {
+	h := w.handler(job.Kind)
+	if h == nil {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+
+	return h(ctx, job)
+}
+
+// rescueLoop makes jobs pending again that have been running for longer than the lock timeout, or dead if they have
+// no attempts left.
+func (w *Worker) rescueLoop(ctx context.Context) {
+	t := time.NewTicker(max(w.lockTimeout/4, w.pollInterval))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if err := pgxkit.Exec(ctx, w.db, `UPDATE pgxkit_jobs
+SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+	finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+	locked_at = NULL, locked_by = NULL, last_error = 'lock timed out'
+WHERE status = 'running' AND queue = $1 AND locked_at < now() - $2::interval`, w.queue, w.lockTimeout); err != nil && ctx.Err() == nil {
+			w.logError(ctx, "rescuing jobs", err)
+		}
+	}
+}
+
+func (w *Worker) logError(ctx context.Context, msg string, err error, attrs ...any) {
+	if w.log == nil {
+		return
+	}
+	w.log.ErrorContext(ctx, msg, append(attrs, slog.Group("error", slog.String("msg", err.Error())))...)
+}
+
+// DefaultBackoff waits 2^attempt seconds, capped at an hour, with up to 10% jitter.
+func DefaultBackoff(attempt int) time.Duration {
+	d := time.Second << min(attempt, 12)
+	if d > _maxBackoff {
+		d = _maxBackoff
+	}
+	return d + rand.N(d/10+1)
+}
+
+type WorkerOption interface {
+	applyToWorker(*Worker)
+}
+
+type WorkerOptionFunc func(*Worker)
+
+func (f WorkerOptionFunc) applyToWorker(w *Worker) { f(w) }
+
+// WithWorkerQueue sets the queue the worker claims jobs from, "default" unless set.
+func WithWorkerQueue(name string) WorkerOptionFunc {
+	return func(w *Worker) { w.queue = name }
+}
+
+// WithConcurrency sets how many jobs are processed at the same time. The default is 1.
+func WithConcurrency(n int) WorkerOptionFunc {
+	return func(w *Worker) { w.concurrency = max(n, 1) }
+}
+
+// WithPollInterval sets how long the worker waits before looking for jobs again when none was due.
+func WithPollInterval(d time.Duration) WorkerOptionFunc {
+	return func(w *Worker) { w.pollInterval = d }
+}
+
+// WithLockTimeout sets after how long a running job is considered abandoned by a crashed worker and made pending
+// again. It must exceed the longest time a job runs. The default is 30 minutes.
+func WithLockTimeout(d time.Duration) WorkerOptionFunc {
+	return func(w *Worker) { w.lockTimeout = d }
+}
+
+// WithBackoff sets the delay before a failed job is retried, given the number of attempts made so far.
+func WithBackoff(fn func(attempt int) time.Duration) WorkerOptionFunc {
+	return func(w *Worker) { w.backoff = fn }
+}
+
+// WithWorkerID sets the identifier recorded as locked_by, the host name and process ID by default.
+func WithWorkerID(id string) WorkerOptionFunc {
+	return func(w *Worker) { w.id = id }
+}
+
+func WithWorkerLogger(log *slog.Logger) WorkerOptionFunc {
+	return func(w *Worker) { w.log = log }
+}
@@ -0,0 +1,37 @@
+package pgxkit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON holds a value stored in a json or jsonb column, so struct fields round-trip typed Go values without
+// marshaling them by hand. It works with pgx as well as database/sql. NULL scans into the zero value.
+type JSON[T any] struct {
+	V T
+}
+
+func NewJSON[T any](v T) JSON[T] { return JSON[T]{V: v} }
+
+func (j JSON[T]) MarshalJSON() ([]byte, error) { return json.Marshal(j.V) }
+
+func (j *JSON[T]) UnmarshalJSON(b []byte) error { return json.Unmarshal(b, &j.V) }
+
+func (j JSON[T]) Value() (driver.Value, error) { return json.Marshal(j.V) }
+
+func (j *JSON[T]) Scan(src any) error {
+	var zero T
+	j.V = zero
+
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, &j.V)
+	case string:
+		return json.Unmarshal([]byte(src), &j.V)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", src)
+	}
+}
@@ -0,0 +1,154 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const _defaultLeaderInterval = 5 * time.Second
+
+// LeaderElector campaigns for a named advisory lock, so that of all replicas running it at most one holds the lock at
+// a time, e.g. to run singleton background workers. The leader checks its lock connection on every heartbeat and
+// cancels the context of OnElected as soon as the check fails; it only releases the lock once that work has returned.
+//
+// Leadership can overlap: when the lock connection breaks, the database releases the lock and another replica may
+// be elected before the old leader notices, which takes up to twice the interval, and the old leader's work runs on
+// until it observes the canceled context. Work that must never run twice guards its writes with a fence, e.g. a
+// version column or a term number incremented by every new leader, rather than relying on the election alone.
+type LeaderElector struct {
+	db         Acquirer
+	name       string
+	key        int64
+	interval   time.Duration
+	onElected  func(ctx context.Context)
+	onResigned func()
+	log        *slog.Logger
+
+	leader atomic.Bool
+}
+
+func NewLeaderElector(db Acquirer, name string, opts ...LeaderOption) *LeaderElector {
+	e := LeaderElector{
+		db:       db,
+		name:     name,
+		key:      AdvisoryLockKey(name),
+		interval: _defaultLeaderInterval,
+	}
+
+	for _, opt := range opts {
+		opt.applyToLeaderElector(&e)
+	}
+
+	return &e
+}
+
+func (e *LeaderElector) IsLeader() bool { return e.leader.Load() }
+
+// Run campaigns until ctx is done and then steps down. It returns nil once ctx is done.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+
+	for {
+		lock, err := TryAdvisoryLock(ctx, e.db, e.key)
+		switch {
+		case err == nil:
+			e.lead(ctx, lock, t.C)
+		case !errors.Is(err, ErrLockNotAcquired) && ctx.Err() == nil && e.log != nil:
+			e.log.WarnContext(ctx, "campaigning for leadership",
+				slog.String("name", e.name),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// lead runs OnElected while the lock is held and ctx is not done, then resigns.
+func (e *LeaderElector) lead(ctx context.Context, lock *AdvisoryLock, heartbeat <-chan time.Time) {
+	e.leader.Store(true)
+	if e.log != nil {
+		e.log.InfoContext(ctx, "elected leader", slog.String("name", e.name))
+	}
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if e.onElected != nil {
+			e.onElected(leadCtx)
+		}
+	}()
+
+	for leadCtx.Err() == nil {
+		select {
+		case <-leadCtx.Done():
+		case <-heartbeat:
+			if err := e.ping(ctx, lock); err != nil && ctx.Err() == nil {
+				if e.log != nil {
+					e.log.WarnContext(ctx, "lost leadership",
+						slog.String("name", e.name),
+						slog.Group("error", slog.String("msg", err.Error())),
+					)
+				}
+				cancel()
+			}
+		}
+	}
+
+	cancel()
+	<-done
+
+	e.leader.Store(false)
+	_ = lock.Release(ctx)
+
+	if e.onResigned != nil {
+		e.onResigned()
+	}
+	if e.log != nil {
+		e.log.InfoContext(ctx, "resigned leadership", slog.String("name", e.name))
+	}
+}
+
+// ping checks that the connection holding the lock, and with it the lock, is still alive.
+func (e *LeaderElector) ping(ctx context.Context, lock *AdvisoryLock) error {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+	return lock.Conn().Ping(ctx)
+}
+
+type LeaderOption interface {
+	applyToLeaderElector(*LeaderElector)
+}
+
+type LeaderOptionFunc func(*LeaderElector)
+
+func (f LeaderOptionFunc) applyToLeaderElector(e *LeaderElector) { f(e) }
+
+// WithOnElected sets the function run on becoming leader. Its context is canceled when leadership is lost or the
+// elector shuts down, and the lock is held until it returns.
+func WithOnElected(fn func(ctx context.Context)) LeaderOptionFunc {
+	return func(e *LeaderElector) { e.onElected = fn }
+}
+
+// WithOnResigned sets the function called after leadership was given up and the lock released.
+func WithOnResigned(fn func()) LeaderOptionFunc {
+	return func(e *LeaderElector) { e.onResigned = fn }
+}
+
+// WithLeaderInterval sets how often followers campaign and the leader checks its lock. The default is 5s.
+func WithLeaderInterval(d time.Duration) LeaderOptionFunc {
+	return func(e *LeaderElector) { e.interval = d }
+}
+
+func WithLeaderLogger(log *slog.Logger) LeaderOptionFunc {
+	return func(e *LeaderElector) { e.log = log }
+}
@@ -0,0 +1,250 @@
+package pgxkit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	_defaultListenerMinBackoff = 100 * time.Millisecond
+	_defaultListenerMaxBackoff = 30 * time.Second
+)
+
+type Notification = pgconn.Notification
+
+// NotificationHandler handles a notification. Handlers run one at a time on the listener's goroutine, so slow work
+// should be handed off to keep notifications flowing.
+type NotificationHandler func(ctx context.Context, n *Notification)
+
+// Listener receives LISTEN/NOTIFY notifications on a dedicated connection and dispatches them to the handler of
+// their channel. Lost connections are reestablished with backoff; since notifications sent in the meantime are
+// lost, the resync hook runs after every (re)connection to catch up, e.g. by polling the table notified about.
+type Listener struct {
+	db         Connector
+	log        *slog.Logger
+	resync     func(ctx context.Context) error
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	handlers map[string]NotificationHandler
+	dirty    bool
+	wake     context.CancelFunc
+}
+
+func NewListener(db Connector, opts ...ListenerOption) *Listener {
+	l := Listener{
+		db:         db,
+		minBackoff: _defaultListenerMinBackoff,
+		maxBackoff: _defaultListenerMaxBackoff,
+		handlers:   make(map[string]NotificationHandler),
+	}
+
+	for _, opt := range opts {
+		opt.applyToListener(&l)
+	}
+
+	return &l
+}
+
+// Listen subscribes h to channel, replacing any handler subscribed before. It may be called while Run is running.
+func (l *Listener) Listen(channel string, h NotificationHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers[channel] = h
+	l.changed()
+}
+
+func (l *Listener) Unlisten(channel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.handlers, channel)
+	l.changed()
+}
+
+// changed interrupts waiting for notifications so the subscriptions are updated. l.mu must be held.
+func (l *Listener) changed() {
+	l.dirty = true
+	if l.wake != nil {
+		l.wake()
+	}
+}
+
+// Run listens until ctx is done, reconnecting whenever the connection fails. It returns nil once ctx is done.
+func (l *Listener) Run(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		established, err := l.run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if established {
+			attempt = 1
+		}
+
+		delay := l.backoff(attempt)
+		if l.log != nil {
+			l.log.WarnContext(ctx, "listener connection lost",
+				slog.Int("attempt", attempt),
+				slog.Duration("delay", delay),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// run serves one connection until it fails, reporting whether it was established and subscribed.
+func (l *Listener) run(ctx context.Context) (bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("connecting: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	listening := make(map[string]bool)
+
+	if err := l.subscribe(ctx, conn, listening); err != nil {
+		return false, err
+	}
+
+	if l.resync != nil {
+		if err := l.resync(ctx); err != nil && l.log != nil {
+			l.log.ErrorContext(ctx, "resyncing listener",
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+	}
+
+	for {
+		waitCtx, cancel := context.WithCancel(ctx)
+
+		l.mu.Lock()
+		if l.dirty {
+			l.mu.Unlock()
+			cancel()
+			if err := l.subscribe(ctx, conn, listening); err != nil {
+				return true, err
+			}
+			continue
+		}
+		l.wake = cancel
+		l.mu.Unlock()
+
+		n, err := conn.WaitForNotification(waitCtx)
+
+		l.mu.Lock()
+		l.wake = nil
+		l.mu.Unlock()
+		cancel()
+
+		switch {
+		case err == nil:
+			l.dispatch(ctx, n)
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case waitCtx.Err() != nil && !conn.IsClosed():
+			// Woken up by a change of subscriptions, handled at the top of the loop.
+		default:
+			return true, fmt.Errorf("waiting for notification: %w", err)
+		}
+	}
+}
+
+// subscribe issues LISTEN and UNLISTEN so the connection listens on exactly the channels with a handler.
+func (l *Listener) subscribe(ctx context.Context, conn *pgx.Conn, listening map[string]bool) error {
+	l.mu.Lock()
+	want := make(map[string]bool, len(l.handlers))
+	for channel := range l.handlers {
+		want[channel] = true
+	}
+	l.dirty = false
+	l.mu.Unlock()
+
+	for channel := range want {
+		if listening[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("listening on %s: %w", channel, err)
+		}
+		listening[channel] = true
+	}
+
+	for channel := range listening {
+		if want[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("unlistening from %s: %w", channel, err)
+		}
+		delete(listening, channel)
+	}
+
+	return nil
+}
+
+func (l *Listener) dispatch(ctx context.Context, n *Notification) {
+	l.mu.Lock()
+	h := l.handlers[n.Channel]
+	l.mu.Unlock()
+
+	if h != nil {
+		h(ctx, n)
+	}
+}
+
+func (l *Listener) backoff(attempt int) time.Duration {
+	if l.maxBackoff <= 0 {
+		return 0
+	}
+	d := l.minBackoff << (attempt - 1)
+	if d <= 0 || d > l.maxBackoff {
+		d = l.maxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Notify sends a notification on channel with payload through pg_notify, so channel names need no quoting.
+func Notify(ctx context.Context, e Execer, channel, payload string) error {
+	return Exec(ctx, e, "SELECT pg_notify($1, $2)", channel, payload)
+}
+
+type ListenerOption interface {
+	applyToListener(*Listener)
+}
+
+type ListenerOptionFunc func(*Listener)
+
+func (f ListenerOptionFunc) applyToListener(l *Listener) { f(l) }
+
+func WithListenerLogger(log *slog.Logger) ListenerOptionFunc {
+	return func(l *Listener) { l.log = log }
+}
+
+// WithListenerResync sets a hook run after every (re)connection, once the channels are listened on, to process what
+// was missed while no connection was listening.
+func WithListenerResync(fn func(ctx context.Context) error) ListenerOptionFunc {
+	return func(l *Listener) { l.resync = fn }
+}
+
+// WithListenerBackoff sets the delay before reconnecting, doubled after every failed attempt up to maxDelay. The
+// defaults are 100ms and 30s.
+func WithListenerBackoff(base, maxDelay time.Duration) ListenerOptionFunc {
+	return func(l *Listener) { l.minBackoff, l.maxBackoff = base, maxDelay }
+}
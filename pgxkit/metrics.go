@@ -0,0 +1,203 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const _defaultMetricsPool = "default"
+
+// poolMetrics observes query durations as a pgx tracer and exposes the statistics of the pool once it is opened.
+type poolMetrics struct {
+	cfg      metricsConfig
+	duration *prometheus.HistogramVec
+	pool     *poolCollector
+}
+
+type metricsConfig struct {
+	registerer prometheus.Registerer
+	namespace  string
+	pool       string
+	buckets    []float64
+}
+
+type queryMetricsKey struct{}
+
+type queryMetricsStart struct {
+	op    string
+	start time.Time
+}
+
+func newPoolMetrics(opts []MetricsOption) *poolMetrics {
+	cfg := metricsConfig{
+		registerer: prometheus.DefaultRegisterer,
+		pool:       _defaultMetricsPool,
+		buckets:    prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt.applyToMetrics(&cfg)
+	}
+
+	return &poolMetrics{
+		cfg: cfg,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of database queries.",
+			Buckets:   cfg.buckets,
+		}, []string{"pool", "operation", "status"}),
+	}
+}
+
+// registerQueries registers the query histogram, reusing one already registered by another client.
+func (m *poolMetrics) registerQueries() error {
+	if err := m.cfg.registerer.Register(m.duration); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return err
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return err
+		}
+		m.duration = existing
+	}
+	return nil
+}
+
+func (m *poolMetrics) registerPool(pool *pgxpool.Pool) error {
+	collector := newPoolCollector(pool, m.cfg)
+	if err := m.cfg.registerer.Register(collector); err != nil {
+		return err
+	}
+	m.pool = collector
+	return nil
+}
+
+// unregisterPool removes the collector of a closed pool, so the client can be opened again. The query histogram
+// stays registered, it is shared with other clients and reused on reopening.
+func (m *poolMetrics) unregisterPool() {
+	if m.pool != nil {
+		m.cfg.registerer.Unregister(m.pool)
+		m.pool = nil
+	}
+}
+
+func (m *poolMetrics) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := operationName(data.SQL)
+	if op == "" {
+		op = "unknown"
+	}
+	return context.WithValue(ctx, queryMetricsKey{}, queryMetricsStart{op: op, start: time.Now()})
+}
+
+func (m *poolMetrics) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryMetricsKey{}).(queryMetricsStart)
+	if !ok {
+		return
+	}
+
+	status := "ok"
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		status = "error"
+	}
+
+	m.duration.WithLabelValues(m.cfg.pool, start.op, status).Observe(time.Since(start.start).Seconds())
+}
+
+// poolCollector exposes pgxpool.Stat, read on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	constructing    *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	canceled        *prometheus.Desc
+	empty           *prometheus.Desc
+	created         *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool, cfg metricsConfig) *poolCollector {
+	labels := prometheus.Labels{"pool": cfg.pool}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(cfg.namespace, "db_pool", name), help, nil, labels)
+	}
+
+	return &poolCollector{
+		pool:            pool,
+		acquired:        desc("acquired_conns", "Number of connections currently acquired from the pool."),
+		idle:            desc("idle_conns", "Number of idle connections in the pool."),
+		constructing:    desc("constructing_conns", "Number of connections being established."),
+		total:           desc("total_conns", "Total number of connections in the pool."),
+		max:             desc("max_conns", "Maximum size of the pool."),
+		acquires:        desc("acquires_total", "Total number of successful acquires from the pool."),
+		acquireDuration: desc("acquire_duration_seconds_total", "Total time spent acquiring connections from the pool."),
+		canceled:        desc("canceled_acquires_total", "Total number of acquires canceled by their context."),
+		empty:           desc("empty_acquires_total", "Total number of acquires that had to wait for a connection."),
+		created:         desc("new_conns_total", "Total number of connections opened."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.constructing
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.canceled
+	ch <- c.empty
+	ch <- c.created
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructing, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.empty, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.created, prometheus.CounterValue, float64(s.NewConnsCount()))
+}
+
+type MetricsOption interface {
+	applyToMetrics(*metricsConfig)
+}
+
+type MetricsOptionFunc func(*metricsConfig)
+
+func (f MetricsOptionFunc) applyToMetrics(c *metricsConfig) { f(c) }
+
+func WithMetricsRegisterer(reg prometheus.Registerer) MetricsOptionFunc {
+	return func(c *metricsConfig) { c.registerer = reg }
+}
+
+func WithMetricsNamespace(ns string) MetricsOptionFunc {
+	return func(c *metricsConfig) { c.namespace = ns }
+}
+
+// WithMetricsPool sets the "pool" label, distinguishing several clients in one process. The default is "default".
+func WithMetricsPool(name string) MetricsOptionFunc {
+	return func(c *metricsConfig) { c.pool = name }
+}
+
+func WithMetricsBuckets(buckets ...float64) MetricsOptionFunc {
+	return func(c *metricsConfig) { c.buckets = buckets }
+}
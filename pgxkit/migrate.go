@@ -0,0 +1,258 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/tern/v2/migrate"
+)
+
+// _historySuffix names the table next to a version table that records when migrations were applied, which the
+// version table of tern does not.
+const _historySuffix = "_history"
+
+// MigrationStatus describes the migrations of a database. It is marshaled to JSON, e.g. for an admin endpoint.
+type MigrationStatus struct {
+	Version int32           `json:"version"`
+	Latest  int32           `json:"latest"`
+	Applied []MigrationInfo `json:"applied"`
+	Pending []MigrationInfo `json:"pending"`
+}
+
+// MigrationInfo is a migration and, if applied, when. Migrations applied before the history was recorded have no
+// time.
+type MigrationInfo struct {
+	Sequence  int32      `json:"sequence"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// VersionedMigrations returns fsys with its migrations versioned in their own table instead of the schema_version
+// table of the application, so migrations shipped by a library are numbered and applied independently of those of
+// the application and of other libraries.
+func VersionedMigrations(fsys fs.FS, versionTable string) fs.FS {
+	return versionedFS{FS: fsys, table: versionTable}
+}
+
+type versionedFS struct {
+	fs.FS
+	table string
+}
+
+// versionTable returns the version table of the migrations of fsys.
+func versionTable(fsys fs.FS) string {
+	if v, ok := fsys.(versionedFS); ok {
+		return v.table
+	}
+	return _defaultVersionTable
+}
+
+// migrator creates a tern migrator on conn with the migrations of fsys, or of its migrations directory if it has one.
+func (c *client) migrator(ctx context.Context, conn *pgx.Conn, fsys fs.FS) (*migrate.Migrator, error) {
+	table := _defaultVersionTable
+	if v, ok := fsys.(versionedFS); ok {
+		table, fsys = v.table, v.FS
+	}
+
+	if c.hasNestedFS(fsys) {
+		var err error
+		if fsys, err = fs.Sub(fsys, _defaultSubtree); err != nil {
+			return nil, fmt.Errorf("sub migrations directory: %w", err)
+		}
+	}
+
+	mg, err := migrate.NewMigrator(ctx, conn, table)
+	if err != nil {
+		return nil, fmt.Errorf("creating migrator: %w", err)
+	}
+
+	if err := mg.LoadMigrations(fsys); err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+
+	return mg, nil
+}
+
+// MigrationStatus returns the current version of the database and the applied and pending migrations of fsys.
+func (c *client) MigrationStatus(ctx context.Context, fsys fs.FS) (*MigrationStatus, error) {
+	conn, err := c.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer c.closeConn(ctx, conn)
+
+	mg, err := c.migrator(ctx, conn, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := mg.GetCurrentVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting current version: %w", err)
+	}
+
+	applied, err := appliedAt(ctx, conn, versionTable(fsys)+_historySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	status := MigrationStatus{Version: current, Latest: int32(len(mg.Migrations))}
+	for _, m := range mg.Migrations {
+		info := MigrationInfo{Sequence: m.Sequence, Name: m.Name}
+		if m.Sequence > current {
+			status.Pending = append(status.Pending, info)
+			continue
+		}
+		if t, ok := applied[m.Sequence]; ok {
+			info.AppliedAt = &t
+		}
+		status.Applied = append(status.Applied, info)
+	}
+
+	return &status, nil
+}
+
+// MigrateDryRun writes the SQL that Migrate would run for act to w, in order and with templates expanded, without
+// running it, so changes can be reviewed before they are applied.
+func (c *client) MigrateDryRun(ctx context.Context, fsys fs.FS, act MigrateAction, w io.Writer) error {
+	conn, err := c.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer c.closeConn(ctx, conn)
+
+	mg, err := c.migrator(ctx, conn, fsys)
+	if err != nil {
+		return err
+	}
+
+	current, err := mg.GetCurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("getting current version: %w", err)
+	}
+
+	target, err := act.target(current, int32(len(mg.Migrations)))
+	if err != nil {
+		return err
+	}
+
+	steps, err := migrationSteps(mg.Migrations, current, target)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		if _, err := fmt.Fprintf(w, "-- %s (%s)\n%s\n\n", step.name, step.direction, step.sql); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type migrationStep struct {
+	name      string
+	direction string
+	sql       string
+}
+
+// migrationSteps returns the migrations run from version from to version to, like tern runs them. Like tern, it
+// rejects versions outside of the migrations, e.g. of a database migrated by a newer release, and migrations without
+// down SQL on the way down.
+func migrationSteps(migrations []*migrate.Migration, from, to int32) ([]migrationStep, error) {
+	last := int32(len(migrations))
+	if from < 0 || from > last {
+		return nil, badVersion("current", from, last)
+	}
+	if to < 0 || to > last {
+		return nil, badVersion("destination", to, last)
+	}
+
+	var steps []migrationStep
+	for v := from; v < to; v++ {
+		m := migrations[v]
+		steps = append(steps, migrationStep{name: m.Name, direction: "up", sql: m.UpSQL})
+	}
+	for v := from; v > to; v-- {
+		m := migrations[v-1]
+		if m.DownSQL == "" {
+			return nil, fmt.Errorf("irreversible migration: %d - %s", m.Sequence, m.Name)
+		}
+		steps = append(steps, migrationStep{name: m.Name, direction: "down", sql: m.DownSQL})
+	}
+	return steps, nil
+}
+
+func badVersion(which string, version, last int32) error {
+	msg := fmt.Sprintf("%s version %d is outside the valid versions of 0 to %d", which, version, last)
+	return migrate.BadVersionError(msg)
+}
+
+func ensureHistoryTable(ctx context.Context, conn *pgx.Conn, table string) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		sequence   integer     PRIMARY KEY,
+		name       text        NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating migration history table: %w", err)
+	}
+	return nil
+}
+
+// recordHistory records the migrations applied since version from in table and forgets those undone.
+func recordHistory(ctx context.Context, conn *pgx.Conn, table string, mg *migrate.Migrator, from int32) error {
+	to, err := mg.GetCurrentVersion(ctx)
+	if err != nil || to == from {
+		return err
+	}
+
+	if err := ensureHistoryTable(ctx, conn, table); err != nil {
+		return err
+	}
+
+	if to < from {
+		_, err := conn.Exec(ctx, "DELETE FROM "+table+" WHERE sequence > $1", to)
+		return err
+	}
+
+	var errs []error
+	for _, m := range mg.Migrations[from:to] {
+		_, err := conn.Exec(ctx, "INSERT INTO "+table+` (sequence, name) VALUES ($1, $2)
+			ON CONFLICT (sequence) DO UPDATE SET name = excluded.name, applied_at = now()`, m.Sequence, m.Name)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// appliedAt returns when the migrations in the history table were applied, or nothing if no history was recorded
+// yet.
+func appliedAt(ctx context.Context, conn *pgx.Conn, table string) (map[int32]time.Time, error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("looking up migration history: %w", err)
+	}
+
+	applied := make(map[int32]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, _ := conn.Query(ctx, "SELECT sequence, applied_at FROM "+table)
+	var seq int32
+	var t time.Time
+	_, err := pgx.ForEachRow(rows, []any{&seq, &t}, func() error {
+		applied[seq] = t
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading migration history: %w", err)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,177 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const _instrumentationName = "github.com/drakelthedragon/toolbox/pgxkit"
+
+// OTelTracer is a pgx tracer starting a client span for every query, batch and copy. Transactions show up as the
+// spans of their BEGIN, COMMIT and ROLLBACK statements. Spans are children of the span in the context, such as the
+// server span of httpkit's tracing middleware, so queries appear within the request that ran them.
+type OTelTracer struct {
+	tracer        trace.Tracer
+	omitStatement bool
+}
+
+func NewOTelTracer(opts ...OTelOption) *OTelTracer {
+	cfg := otelConfig{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt.applyToOTel(&cfg)
+	}
+
+	return &OTelTracer{
+		tracer:        cfg.provider.Tracer(_instrumentationName),
+		omitStatement: cfg.omitStatement,
+	}
+}
+
+func (t *OTelTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := operationName(data.SQL)
+
+	attrs := append(connAttributes(conn), semconv.DBOperationName(op))
+	if !t.omitStatement {
+		attrs = append(attrs, semconv.DBQueryText(data.SQL))
+	}
+
+	ctx, _ = t.tracer.Start(ctx, spanTarget(op, conn),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx
+}
+
+func (t *OTelTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.CommandTag.Select() {
+		span.SetAttributes(semconv.DBResponseReturnedRows(int(data.CommandTag.RowsAffected())))
+	}
+	endSpan(span, data.Err)
+}
+
+func (t *OTelTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	attrs := append(connAttributes(conn), semconv.DBOperationName("BATCH"))
+	if data.Batch != nil {
+		attrs = append(attrs, semconv.DBOperationBatchSize(data.Batch.Len()))
+	}
+
+	ctx, _ = t.tracer.Start(ctx, spanTarget("BATCH", conn),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx
+}
+
+func (t *OTelTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attrs := []attribute.KeyValue{semconv.DBOperationName(operationName(data.SQL))}
+	if !t.omitStatement {
+		attrs = append(attrs, semconv.DBQueryText(data.SQL))
+	}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("query", trace.WithAttributes(attrs...))
+}
+
+func (t *OTelTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+func (t *OTelTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	table := data.TableName.Sanitize()
+
+	ctx, _ = t.tracer.Start(ctx, "COPY "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(connAttributes(conn),
+			semconv.DBOperationName("COPY"),
+			semconv.DBCollectionName(table),
+		)...),
+	)
+
+	return ctx
+}
+
+func (t *OTelTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+func connAttributes(conn *pgx.Conn) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.DBSystemNamePostgreSQL}
+	if conn == nil {
+		return attrs
+	}
+
+	cfg := conn.Config()
+	return append(attrs,
+		semconv.DBNamespace(cfg.Database),
+		semconv.ServerAddress(cfg.Host),
+		semconv.ServerPort(int(cfg.Port)),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		var pgerr *pgconn.PgError
+		if errors.As(err, &pgerr) {
+			span.SetAttributes(semconv.DBResponseStatusCode(pgerr.Code))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// operationName returns the first keyword of sql, e.g. SELECT.
+func operationName(sql string) string {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+	if i := strings.IndexAny(sql, " \t\r\n;("); i >= 0 {
+		sql = sql[:i]
+	}
+	return strings.ToUpper(sql)
+}
+
+func spanTarget(op string, conn *pgx.Conn) string {
+	if op == "" {
+		op = "postgresql"
+	}
+	if conn != nil && conn.Config().Database != "" {
+		return op + " " + conn.Config().Database
+	}
+	return op
+}
+
+type otelConfig struct {
+	provider      trace.TracerProvider
+	omitStatement bool
+}
+
+type OTelOption interface {
+	applyToOTel(*otelConfig)
+}
+
+type OTelOptionFunc func(*otelConfig)
+
+func (f OTelOptionFunc) applyToOTel(c *otelConfig) { f(c) }
+
+// WithOTelTracerProvider sets the provider spans are created with. The default is the global provider.
+func WithOTelTracerProvider(tp trace.TracerProvider) OTelOptionFunc {
+	return func(c *otelConfig) { c.provider = tp }
+}
+
+// WithOTelOmitStatement leaves the SQL text out of spans, e.g. when statements embed sensitive literals.
+func WithOTelOmitStatement() OTelOptionFunc {
+	return func(c *otelConfig) { c.omitStatement = true }
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS pgxkit_outbox (
+	id              BIGSERIAL PRIMARY KEY,
+	idempotency_key TEXT NOT NULL UNIQUE,
+	topic           TEXT NOT NULL,
+	key             TEXT NOT NULL DEFAULT '',
+	payload         JSONB NOT NULL,
+	headers         JSONB NOT NULL DEFAULT '{}',
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT,
+	created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+	published_at    TIMESTAMPTZ,
+	dead_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS pgxkit_outbox_pending_idx ON pgxkit_outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS pgxkit_outbox;
@@ -0,0 +1,154 @@
+// Package outbox implements the transactional outbox pattern. Events are written to an outbox table within the
+// transaction of the change they describe, so they are recorded if and only if the change commits, and a Relay hands
+// them to a Publisher such as a message broker afterwards. Delivery is at least once: an event may be published again
+// if the relay fails after publishing it, so consumers deduplicate by the event's idempotency key.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+// Channel is notified when events are written, so relays given a pgxkit.Listener pick them up without polling.
+const Channel = "pgxkit_outbox"
+
+//go:embed migrations/*.sql
+var _migrations embed.FS
+
+// Migrations holds the tern migration creating the outbox table. It is versioned in its own table, so it is applied
+// with pgxkit.Migrator.Migrate independently of the migrations of the application. Applications may instead copy it
+// into their own migrations or execute Schema.
+var Migrations = pgxkit.VersionedMigrations(_migrations, "public.pgxkit_outbox_schema_version")
+
+//go:embed migrations/001_create_pgxkit_outbox.sql
+var _createMigration string
+
+// Schema creates the outbox table if it does not exist. It is the up part of the migration in Migrations.
+var Schema = upMigration(_createMigration)
+
+// upMigration returns the part of a tern migration above the "---- create above / drop below ----" line.
+func upMigration(sql string) string {
+	up, _, _ := strings.Cut(sql, "---- create above / drop below ----")
+	return strings.TrimSpace(up)
+}
+
+type Event struct {
+	ID             int64             `db:"id"`
+	IdempotencyKey string            `db:"idempotency_key"`
+	Topic          string            `db:"topic"`
+	Key            string            `db:"key"`
+	Payload        json.RawMessage   `db:"payload"`
+	Headers        map[string]string `db:"headers"`
+	Attempts       int32             `db:"attempts"`
+	CreatedAt      time.Time         `db:"created_at"`
+}
+
+const _eventColumns = "id, idempotency_key, topic, key, payload, headers, attempts, created_at"
+
+// Publisher delivers events, e.g. to a message broker. Returning nil confirms delivery.
+type Publisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
+
+type PublisherFunc func(ctx context.Context, e *Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e *Event) error { return f(ctx, e) }
+
+type writeConfig struct {
+	key            string
+	idempotencyKey string
+	headers        map[string]string
+}
+
+// Write records an event for topic with payload encoded as JSON. db should be the transaction making the change the
+// event describes. Writing an event whose idempotency key was already written is a no-op. The idempotency key of the
+// event is returned.
+func Write(ctx context.Context, db pgxkit.Execer, topic string, payload any, opts ...WriteOption) (string, error) {
+	var cfg writeConfig
+	for _, opt := range opts {
+		opt.applyToWrite(&cfg)
+	}
+
+	if cfg.idempotencyKey == "" {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return "", err
+		}
+		cfg.idempotencyKey = key
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("encoding payload: %w", err)
+	}
+
+	headers := cfg.headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	if err := pgxkit.Exec(ctx, db, `INSERT INTO pgxkit_outbox (idempotency_key, topic, key, payload, headers)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (idempotency_key) DO NOTHING`, cfg.idempotencyKey, topic, cfg.key, b, headers); err != nil {
+		return "", err
+	}
+
+	// Notifications sent in a transaction are delivered when it commits.
+	if err := pgxkit.Notify(ctx, db, Channel, topic); err != nil {
+		return "", err
+	}
+
+	return cfg.idempotencyKey, nil
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating idempotency key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Requeue makes a dead event pending again with a fresh set of attempts. It is published in the order of its ID, so
+// ahead of events written after it that are still pending. It returns pgxkit.ErrNotFound if there is no dead event
+// with id.
+func Requeue(ctx context.Context, db pgxkit.Execer, id int64) error {
+	return pgxkit.ExecOne(ctx, db, `UPDATE pgxkit_outbox SET attempts = 0, dead_at = NULL
+WHERE id = $1 AND dead_at IS NOT NULL`, id)
+}
+
+// Prune deletes events published before the given time. Dead events are kept until they are requeued.
+func Prune(ctx context.Context, db pgxkit.Execer, before time.Time) error {
+	return pgxkit.Exec(ctx, db, "DELETE FROM pgxkit_outbox WHERE published_at < $1", before)
+}
+
+type WriteOption interface {
+	applyToWrite(*writeConfig)
+}
+
+type WriteOptionFunc func(*writeConfig)
+
+func (f WriteOptionFunc) applyToWrite(c *writeConfig) { f(c) }
+
+// WithKey sets the key of the event, e.g. the ID of the aggregate, which brokers use for partitioning.
+func WithKey(key string) WriteOptionFunc {
+	return func(c *writeConfig) { c.key = key }
+}
+
+// WithIdempotencyKey sets the idempotency key of the event instead of a random one, so retried requests write it
+// only once.
+func WithIdempotencyKey(key string) WriteOptionFunc {
+	return func(c *writeConfig) { c.idempotencyKey = key }
+}
+
+func WithHeaders(headers map[string]string) WriteOptionFunc {
+	return func(c *writeConfig) { c.headers = headers }
+}
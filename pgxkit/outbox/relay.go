@@ -0,0 +1,227 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+const (
+	_defaultBatchSize    = 100
+	_defaultPollInterval = time.Second
+	_defaultMaxAttempts  = 25
+	_maxBackoff          = time.Minute
+)
+
+// Relay publishes the events written to the outbox in the order they were written. Several relays may run side by
+// side; each batch of events is locked by one of them, but order across batches is then no longer guaranteed. An
+// event failing to publish holds back the events after it, and is retried with backoff until it runs out of attempts
+// and is marked dead, so a poison event does not block the outbox forever.
+type Relay struct {
+	db           pgxkit.Beginner
+	pub          Publisher
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int32
+	log          *slog.Logger
+	wake         chan struct{}
+}
+
+func NewRelay(db pgxkit.Beginner, pub Publisher, opts ...RelayOption) *Relay {
+	r := Relay{
+		db:           db,
+		pub:          pub,
+		batchSize:    _defaultBatchSize,
+		pollInterval: _defaultPollInterval,
+		maxAttempts:  _defaultMaxAttempts,
+		wake:         make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt.applyToRelay(&r)
+	}
+
+	return &r
+}
+
+// Run publishes events until ctx is done. It polls the outbox, and is also woken by notifications if the relay was
+// created with WithRelayListener.
+func (r *Relay) Run(ctx context.Context) error {
+	var failures int
+
+	for {
+		n, failed, err := r.relay(ctx)
+		if err != nil && ctx.Err() == nil && r.log != nil {
+			r.log.ErrorContext(ctx, "relaying outbox events",
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+
+		if err != nil || failed {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		// A full batch suggests more events are waiting.
+		if failures == 0 && n == r.batchSize {
+			continue
+		}
+
+		// While failing, new events do not cut the backoff short, as they queue behind the failed one.
+		delay, wake := r.pollInterval, r.wake
+		if failures > 0 {
+			delay, wake = backoff(r.pollInterval, failures), nil
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-wake:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// backoff doubles the poll interval with every consecutive failure, up to a minute.
+func backoff(interval time.Duration, failures int) time.Duration {
+	d := interval << min(failures-1, 16)
+	if d < interval || d > _maxBackoff {
+		d = max(_maxBackoff, interval)
+	}
+	return d
+}
+
+// relay publishes one batch of events, stopping at the first failure so later events are not published before it.
+// Events that ran out of attempts are marked dead and skipped. It returns the number of events in the batch and
+// whether an event failed and is to be retried.
+func (r *Relay) relay(ctx context.Context) (int, bool, error) {
+	var (
+		n      int
+		failed bool
+	)
+
+	err := pgxkit.WithTx(ctx, r.db, func(tx pgxkit.Tx) error {
+		events, err := pgxkit.Query[Event](ctx, tx, `SELECT `+_eventColumns+` FROM pgxkit_outbox
+WHERE published_at IS NULL AND dead_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED`, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(events))
+		var failures []failure
+		for i := range events {
+			e := &events[i]
+
+			pubErr := r.pub.Publish(ctx, e)
+			if pubErr == nil {
+				ids = append(ids, e.ID)
+				continue
+			}
+
+			dead := e.Attempts+1 >= r.maxAttempts
+			if err := pgxkit.Exec(ctx, tx, `UPDATE pgxkit_outbox
+SET attempts = attempts + 1, last_error = $2, dead_at = CASE WHEN $3::boolean THEN now() END
+WHERE id = $1`, e.ID, pubErr.Error(), dead); err != nil {
+				return err
+			}
+
+			failures = append(failures, failure{event: e, err: pubErr, dead: dead})
+			if !dead {
+				failed = true
+				break
+			}
+		}
+
+		if len(ids) > 0 {
+			if err := pgxkit.Exec(ctx, tx, "UPDATE pgxkit_outbox SET published_at = now() WHERE id = ANY($1)", ids); err != nil {
+				return err
+			}
+		}
+
+		n = len(events)
+
+		// Failures are recorded with the transaction, so they are only logged here.
+		for _, f := range failures {
+			r.logFailure(ctx, f)
+		}
+
+		return nil
+	})
+
+	return n, failed, err
+}
+
+type failure struct {
+	event *Event
+	err   error
+	dead  bool
+}
+
+func (r *Relay) logFailure(ctx context.Context, f failure) {
+	if r.log == nil {
+		return
+	}
+
+	attrs := []any{
+		slog.Int64("event", f.event.ID),
+		slog.String("topic", f.event.Topic),
+		slog.Int("attempt", int(f.event.Attempts)+1),
+		slog.Group("error", slog.String("msg", f.err.Error())),
+	}
+
+	if f.dead {
+		r.log.ErrorContext(ctx, "outbox event is dead", attrs...)
+	} else {
+		r.log.WarnContext(ctx, "publishing outbox event", attrs...)
+	}
+}
+
+type RelayOption interface {
+	applyToRelay(*Relay)
+}
+
+type RelayOptionFunc func(*Relay)
+
+func (f RelayOptionFunc) applyToRelay(r *Relay) { f(r) }
+
+// WithBatchSize sets how many events are locked and published per transaction. The default is 100.
+func WithBatchSize(n int) RelayOptionFunc {
+	return func(r *Relay) { r.batchSize = max(n, 1) }
+}
+
+// WithPollInterval sets how long the relay waits before looking for events again when the outbox was drained.
+func WithPollInterval(d time.Duration) RelayOptionFunc {
+	return func(r *Relay) { r.pollInterval = d }
+}
+
+// WithMaxAttempts sets how often an event is tried before it is marked dead and skipped. Failed events are retried
+// after the poll interval, doubled with every consecutive failure up to a minute. The default is 25.
+func WithMaxAttempts(n int32) RelayOptionFunc {
+	return func(r *Relay) { r.maxAttempts = max(n, 1) }
+}
+
+// WithRelayListener subscribes the relay to Channel on l, so events are relayed as soon as they are committed rather
+// than on the next poll. The listener must be run by the caller.
+func WithRelayListener(l *pgxkit.Listener) RelayOptionFunc {
+	return func(r *Relay) {
+		l.Listen(Channel, func(context.Context, *pgxkit.Notification) {
+			select {
+			case r.wake <- struct{}{}:
+			default:
+			}
+		})
+	}
+}
+
+func WithRelayLogger(log *slog.Logger) RelayOptionFunc {
+	return func(r *Relay) { r.log = log }
+}
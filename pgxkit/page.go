@@ -0,0 +1,127 @@
+package pgxkit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// OffsetPage is a page of an offset paginated query with the total number of rows, as needed by classic paged UIs.
+type OffsetPage[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+// Pages returns the number of pages of Limit rows.
+func (p OffsetPage[T]) Pages() int64 {
+	if p.Limit <= 0 {
+		return 0
+	}
+	return (p.Total + int64(p.Limit) - 1) / int64(p.Limit)
+}
+
+func (p OffsetPage[T]) HasNext() bool { return int64(p.Offset+len(p.Items)) < p.Total }
+
+type pageQueryer interface {
+	Queryer
+	BatchSender
+}
+
+// QueryPage returns limit rows of the query sql starting at offset, scanned into structs by name like Query, and the
+// total number of rows of the query. sql is wrapped as a subquery and should have an ORDER BY clause but no LIMIT or
+// OFFSET. By default the rows and the count are queried in one batch; see WithPageWindowCount.
+func QueryPage[T any](ctx context.Context, q pageQueryer, sql string, args []any, limit, offset int, opts ...PageOption) (OffsetPage[T], error) {
+	var cfg pageConfig
+	for _, opt := range opts {
+		opt.applyToPage(&cfg)
+	}
+
+	page := OffsetPage[T]{Limit: limit, Offset: offset}
+	limitSQL := " LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+
+	if !cfg.window {
+		b := NewBatch()
+		items := BatchQuery[T](b, "SELECT * FROM ("+sql+") AS page"+limitSQL, args...)
+		total := BatchQueryValue[int64](b, countSQL(sql), args...)
+
+		if err := b.Send(ctx, q); err != nil {
+			return page, err
+		}
+
+		page.Items, _ = items.Value()
+		page.Total, _ = total.Value()
+
+		return page, nil
+	}
+
+	rows, _ := q.Query(ctx, "SELECT *, count(*) OVER () FROM ("+sql+") AS page"+limitSQL, args...)
+
+	var total int64
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (T, error) {
+		return pgx.RowToStructByName[T](totalRow{CollectableRow: row, total: &total})
+	})
+	if err != nil {
+		return page, mapErr(err)
+	}
+
+	// Past the last page no row carries the total, so it is counted separately.
+	if len(items) == 0 && offset > 0 {
+		if total, err = Count(ctx, q, sql, args...); err != nil {
+			return page, err
+		}
+	}
+
+	page.Items, page.Total = items, total
+
+	return page, nil
+}
+
+// totalRow hides the trailing window count column from struct scanning and scans it into total.
+type totalRow struct {
+	pgx.CollectableRow
+	total *int64
+}
+
+func (r totalRow) FieldDescriptions() []pgconn.FieldDescription {
+	fds := r.CollectableRow.FieldDescriptions()
+	return fds[:len(fds)-1]
+}
+
+func (r totalRow) Scan(dest ...any) error {
+	return r.CollectableRow.Scan(append(dest, r.total)...)
+}
+
+func (r totalRow) Values() ([]any, error) {
+	vals, err := r.CollectableRow.Values()
+	if err != nil {
+		return nil, err
+	}
+	return vals[:len(vals)-1], nil
+}
+
+func (r totalRow) RawValues() [][]byte {
+	raw := r.CollectableRow.RawValues()
+	return raw[:len(raw)-1]
+}
+
+type pageConfig struct {
+	window bool
+}
+
+type PageOption interface {
+	applyToPage(*pageConfig)
+}
+
+type PageOptionFunc func(*pageConfig)
+
+func (f PageOptionFunc) applyToPage(c *pageConfig) { f(c) }
+
+// WithPageWindowCount counts the rows with a count(*) OVER () window in the page query itself instead of a separate
+// count query in the batch, so the query is planned and run only once.
+func WithPageWindowCount() PageOptionFunc {
+	return func(c *pageConfig) { c.window = true }
+}
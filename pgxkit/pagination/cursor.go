@@ -0,0 +1,59 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCursor is returned for cursors that were not produced by EncodeCursor or do not fit the keyset.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type cursor struct {
+	Backward bool              `json:"b,omitempty"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+// EncodeCursor encodes the sort key values of a row as an opaque, URL-safe cursor. Backward cursors page towards the
+// start of the result.
+func EncodeCursor(backward bool, values ...any) (string, error) {
+	c := cursor{Backward: backward, Values: make([]json.RawMessage, len(values))}
+
+	for i, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("encoding cursor value: %w", err)
+		}
+		c.Values[i] = b
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encoding cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decodes a cursor into dst, pointers to values of the types the sort keys were encoded from, and
+// reports whether it is a backward cursor.
+func DecodeCursor(s string, dst ...any) (bool, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return false, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || len(c.Values) != len(dst) {
+		return false, ErrInvalidCursor
+	}
+
+	for i, v := range c.Values {
+		if err := json.Unmarshal(v, dst[i]); err != nil {
+			return false, ErrInvalidCursor
+		}
+	}
+
+	return c.Backward, nil
+}
@@ -0,0 +1,69 @@
+// Package pagination pages through query results with keyset pagination: rather than skipping rows with OFFSET,
+// every page continues after the sort keys of the last row seen, which stays fast on deep pages and does not skip or
+// repeat rows when rows are inserted concurrently. Positions are passed to clients as opaque cursors.
+package pagination
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Column is a sort key of a Keyset.
+type Column struct {
+	Name string
+	Desc bool
+}
+
+func Asc(name string) Column  { return Column{Name: name} }
+func Desc(name string) Column { return Column{Name: name, Desc: true} }
+
+// Keyset is the ordering of a paginated query. Its columns must identify rows uniquely, typically by ending with the
+// primary key, or rows sharing sort keys at a page boundary are skipped.
+type Keyset struct {
+	columns []Column
+}
+
+func NewKeyset(columns ...Column) Keyset { return Keyset{columns: columns} }
+
+// Where returns the condition selecting the rows after the cursor values, bound as the parameters $first, $first+1
+// and so on, in the order of the columns. Backward selects the rows before them instead.
+//
+// Columns sorted in the same direction could be compared as a row value; the expanded form used here also supports
+// mixed directions: (a > $1) OR (a = $1 AND b > $2) ...
+func (k Keyset) Where(first int, backward bool) string {
+	terms := make([]string, len(k.columns))
+
+	for i, col := range k.columns {
+		parts := make([]string, 0, i+1)
+		for j := range i {
+			parts = append(parts, ident(k.columns[j].Name)+" = $"+strconv.Itoa(first+j))
+		}
+
+		op := ">"
+		if col.Desc != backward {
+			op = "<"
+		}
+		parts = append(parts, ident(col.Name)+" "+op+" $"+strconv.Itoa(first+i))
+
+		terms[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+// OrderBy returns the ORDER BY clause of the keyset, reversed when paging backward.
+func (k Keyset) OrderBy(backward bool) string {
+	terms := make([]string, len(k.columns))
+	for i, col := range k.columns {
+		dir := " ASC"
+		if col.Desc != backward {
+			dir = " DESC"
+		}
+		terms[i] = ident(col.Name) + dir
+	}
+	return "ORDER BY " + strings.Join(terms, ", ")
+}
+
+func ident(name string) string { return pgx.Identifier(strings.Split(name, ".")).Sanitize() }
@@ -0,0 +1,87 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/drakelthedragon/toolbox/pgxkit"
+)
+
+// Page is a page of items with the cursors of the adjacent pages, empty at either end.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Query returns the page of limit rows of the query sql continuing at cursor, or the first page for an empty
+// cursor. sql must not have an ORDER BY or LIMIT clause of its own and is wrapped as a subquery, so the keyset
+// columns are referenced by their names in its select list. keys returns the sort key values of an item in the order
+// of the keyset columns.
+func Query[T any](ctx context.Context, q pgxkit.Queryer, ks Keyset, keys func(T) []any, cursor string, limit int, sql string, args ...any) (Page[T], error) {
+	var page Page[T]
+
+	backward := false
+	query := "SELECT * FROM (" + sql + ") AS page "
+
+	if cursor != "" {
+		var zero T
+		dst := make([]any, 0, len(ks.columns))
+		for _, v := range keys(zero) {
+			dst = append(dst, reflect.New(reflect.TypeOf(v)).Interface())
+		}
+
+		var err error
+		if backward, err = DecodeCursor(cursor, dst...); err != nil {
+			return page, err
+		}
+
+		query += "WHERE " + ks.Where(len(args)+1, backward) + " "
+		for _, d := range dst {
+			args = append(args, reflect.ValueOf(d).Elem().Interface())
+		}
+	}
+
+	query += ks.OrderBy(backward) + " LIMIT " + strconv.Itoa(limit+1)
+
+	items, err := pgxkit.Query[T](ctx, q, query, args...)
+	if err != nil {
+		return page, err
+	}
+
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	if backward {
+		slices.Reverse(items)
+	}
+	page.Items = items
+
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	// Forward, there is a previous page if we came from a cursor and a next one if more rows were found; backward the
+	// other way around.
+	hasNext, hasPrev := more, cursor != ""
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+
+	if hasNext {
+		if page.NextCursor, err = EncodeCursor(false, keys(items[len(items)-1])...); err != nil {
+			return page, fmt.Errorf("encoding next cursor: %w", err)
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = EncodeCursor(true, keys(items[0])...); err != nil {
+			return page, fmt.Errorf("encoding previous cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
@@ -3,7 +3,10 @@ package pgxkit
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/fs"
+	"iter"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -12,17 +15,57 @@ import (
 )
 
 var (
-	ErrNotFound      = errors.New("not found")
-	ErrAlreadyExists = errors.New("already exists")
+	ErrNotFound             = errors.New("not found")
+	ErrAlreadyExists        = errors.New("already exists")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrDeadlock             = errors.New("deadlock detected")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrTimeout              = errors.New("timeout")
+	ErrCanceled             = errors.New("canceled")
 )
 
 type NamedArgs = pgx.NamedArgs
 
+type (
+	TxOptions        = pgx.TxOptions
+	TxIsoLevel       = pgx.TxIsoLevel
+	TxAccessMode     = pgx.TxAccessMode
+	TxDeferrableMode = pgx.TxDeferrableMode
+)
+
+const (
+	Serializable    = pgx.Serializable
+	RepeatableRead  = pgx.RepeatableRead
+	ReadCommitted   = pgx.ReadCommitted
+	ReadUncommitted = pgx.ReadUncommitted
+	ReadWrite       = pgx.ReadWrite
+	ReadOnly        = pgx.ReadOnly
+	Deferrable      = pgx.Deferrable
+	NotDeferrable   = pgx.NotDeferrable
+)
+
+type QueryExecMode = pgx.QueryExecMode
+
+const (
+	QueryExecModeCacheStatement = pgx.QueryExecModeCacheStatement
+	QueryExecModeCacheDescribe  = pgx.QueryExecModeCacheDescribe
+	QueryExecModeDescribeExec   = pgx.QueryExecModeDescribeExec
+	QueryExecModeExec           = pgx.QueryExecModeExec
+	QueryExecModeSimpleProtocol = pgx.QueryExecModeSimpleProtocol
+)
+
 type Beginner interface {
 	// Begin starts a new pgx.Tx. It may be a true transaction or a pseudo nested transaction implemented by savepoints.
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
+type TxBeginner interface {
+	// BeginTx starts a new pgx.Tx with the given isolation level, access mode and deferrable mode.
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
 type Copier interface {
 	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 }
@@ -46,6 +89,14 @@ type Opener interface {
 
 type Closer interface{ Close() }
 
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
 type Acquirer interface {
 	Acquire(ctx context.Context) (*pgxpool.Conn, error)
 }
@@ -56,10 +107,13 @@ type Connector interface {
 
 type Migrator interface {
 	Migrate(ctx context.Context, fsys fs.FS, act MigrateAction) error
+	MigrationStatus(ctx context.Context, fsys fs.FS) (*MigrationStatus, error)
+	MigrateDryRun(ctx context.Context, fsys fs.FS, act MigrateAction, w io.Writer) error
 }
 
 type DB interface {
 	Beginner
+	TxBeginner
 	Copier
 	Queryer
 	Execer
@@ -83,15 +137,27 @@ type Client interface {
 	Connector
 	DB
 	Migrator
+	HealthChecker
+	Shutdowner
 }
 
 func Open(ctx context.Context, url string) (*pgxpool.Pool, error) {
-	db, err := pgxpool.New(ctx, url)
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	return OpenConfig(ctx, cfg)
+}
+
+// OpenConfig creates a pool from cfg and pings the database.
+func OpenConfig(ctx context.Context, cfg *pgxpool.Config) (*pgxpool.Pool, error) {
+	db, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	if err = db.Ping(ctx); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -116,11 +182,107 @@ func QueryValue[T any](ctx context.Context, q Queryer, sql string, args ...any)
 	return val, mapErr(err)
 }
 
+// QueryValues returns the single column of every row, e.g. all IDs matching a condition.
+func QueryValues[T any](ctx context.Context, q Queryer, sql string, args ...any) ([]T, error) {
+	rows, _ := q.Query(ctx, sql, args...)
+	vals, err := pgx.CollectRows(rows, pgx.RowTo[T])
+	return vals, mapErr(err)
+}
+
+// QueryMap scans rows into structs by name, like Query, and indexes them by key. Later rows replace earlier ones with
+// the same key.
+func QueryMap[K comparable, T any](ctx context.Context, q Queryer, key func(T) K, sql string, args ...any) (map[K]T, error) {
+	m := make(map[K]T)
+	for v, err := range QueryIter[T](ctx, q, sql, args...) {
+		if err != nil {
+			return nil, err
+		}
+		m[key(v)] = v
+	}
+	return m, nil
+}
+
+// QueryGroups scans rows into structs by name, like Query, and groups them by key, keeping the order of the rows
+// within each group.
+func QueryGroups[K comparable, T any](ctx context.Context, q Queryer, key func(T) K, sql string, args ...any) (map[K][]T, error) {
+	m := make(map[K][]T)
+	for v, err := range QueryIter[T](ctx, q, sql, args...) {
+		if err != nil {
+			return nil, err
+		}
+		k := key(v)
+		m[k] = append(m[k], v)
+	}
+	return m, nil
+}
+
+// Exists reports whether the query sql returns any row.
+func Exists(ctx context.Context, q Queryer, sql string, args ...any) (bool, error) {
+	return QueryValue[bool](ctx, q, "SELECT EXISTS ("+sql+")", args...)
+}
+
+// Count returns the number of rows of the query sql.
+func Count(ctx context.Context, q Queryer, sql string, args ...any) (int64, error) {
+	return QueryValue[int64](ctx, q, countSQL(sql), args...)
+}
+
+func countSQL(sql string) string { return "SELECT count(*) FROM (" + sql + ") AS count" }
+
+// QueryIter scans rows into T by name as they are iterated, so large results are processed without collecting them
+// first. An error, of the query or of scanning a row, is yielded with the zero T and ends the iteration. The rows are
+// closed when the loop ends, including on break.
+func QueryIter[T any](ctx context.Context, q Queryer, sql string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := q.Query(ctx, sql, args...)
+		if err != nil {
+			yield(zero, mapErr(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v, err := pgx.RowToStructByName[T](rows)
+			if err != nil {
+				yield(zero, mapErr(err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, mapErr(err))
+		}
+	}
+}
+
 func Exec(ctx context.Context, e Execer, sql string, args ...any) error {
 	_, err := e.Exec(ctx, sql, args...)
 	return mapErr(err)
 }
 
+// ExecAffected returns the number of rows affected by the statement.
+func ExecAffected(ctx context.Context, e Execer, sql string, args ...any) (int64, error) {
+	tag, err := e.Exec(ctx, sql, args...)
+	return tag.RowsAffected(), mapErr(err)
+}
+
+// ExecOne returns ErrNotFound if the statement affected no row, e.g. an UPDATE or DELETE by a primary key that does
+// not exist.
+func ExecOne(ctx context.Context, e Execer, sql string, args ...any) error {
+	n, err := ExecAffected(ctx, e, sql, args...)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func mapErr(err error) error {
 	var pgerr *pgconn.PgError
 
@@ -131,17 +293,38 @@ func mapErr(err error) error {
 		return ErrNotFound
 	case errors.As(err, &pgerr):
 		return mapCode(pgerr)
+	case errors.Is(err, ErrTimeout), errors.Is(err, ErrCanceled):
+		return err
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
 	default:
 		return err
 	}
 }
 
+// mapCode maps well-known error codes to sentinels. Constraint violations are returned as *ConstraintError and
+// transaction conflicts keep the *pgconn.PgError in the chain, so the retry of WithTxRetry still recognizes them.
 func mapCode(pgerr *pgconn.PgError) error {
 	switch pgerr.Code {
 	case pgerrcode.NoData, pgerrcode.NoDataFound:
 		return ErrNotFound
 	case pgerrcode.UniqueViolation:
-		return ErrAlreadyExists
+		return newConstraintError(ErrAlreadyExists, pgerr)
+	case pgerrcode.ForeignKeyViolation:
+		return newConstraintError(ErrForeignKeyViolation, pgerr)
+	case pgerrcode.CheckViolation:
+		return newConstraintError(ErrCheckViolation, pgerr)
+	case pgerrcode.NotNullViolation:
+		return newConstraintError(ErrNotNullViolation, pgerr)
+	case pgerrcode.QueryCanceled:
+		// Reported when statement_timeout expires.
+		return fmt.Errorf("%w: %w", ErrTimeout, pgerr)
+	case pgerrcode.DeadlockDetected:
+		return fmt.Errorf("%w: %w", ErrDeadlock, pgerr)
+	case pgerrcode.SerializationFailure:
+		return fmt.Errorf("%w: %w", ErrSerializationFailure, pgerr)
 	default:
 		return pgerr
 	}
@@ -0,0 +1,92 @@
+package pgxkit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryLogger is a pgx.QueryTracer logging every statement with its duration, affected rows and error. Statements
+// are logged at the configured level, debug by default, and failed ones at error level, so the log can be toggled
+// through the level of the logger or a slog.LevelVar given to WithQueryLogLevel.
+type QueryLogger struct {
+	log        *slog.Logger
+	level      slog.Leveler
+	redactArgs bool
+}
+
+type queryLogKey struct{}
+
+type queryLogStart struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+func NewQueryLogger(log *slog.Logger, opts ...QueryLoggerOption) *QueryLogger {
+	l := QueryLogger{log: log, level: slog.LevelDebug}
+	for _, opt := range opts {
+		opt.applyToQueryLogger(&l)
+	}
+	return &l
+}
+
+func (l *QueryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryLogKey{}, queryLogStart{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (l *QueryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryLogKey{}).(queryLogStart)
+	if !ok {
+		return
+	}
+
+	level := l.level.Level()
+	if data.Err != nil {
+		level = slog.LevelError
+	}
+
+	if !l.log.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", start.sql),
+		l.argsAttr(start.args),
+		slog.Duration("duration", time.Since(start.start)),
+		slog.Int64("rows", data.CommandTag.RowsAffected()),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, slog.Group("error", slog.String("msg", data.Err.Error())))
+	}
+
+	l.log.LogAttrs(ctx, level, "query", attrs...)
+}
+
+func (l *QueryLogger) argsAttr(args []any) slog.Attr {
+	if l.redactArgs {
+		return slog.Int("args", len(args))
+	}
+	return slog.Any("args", args)
+}
+
+type QueryLoggerOption interface {
+	applyToQueryLogger(*QueryLogger)
+}
+
+type QueryLoggerOptionFunc func(*QueryLogger)
+
+func (f QueryLoggerOptionFunc) applyToQueryLogger(l *QueryLogger) { f(l) }
+
+// WithQueryLogLevel sets the level successful statements are logged at. Passing a *slog.LevelVar allows changing it
+// at runtime.
+func WithQueryLogLevel(level slog.Leveler) QueryLoggerOptionFunc {
+	return func(l *QueryLogger) { l.level = level }
+}
+
+// WithQueryLogRedactArgs logs only the number of arguments instead of their values, which may be sensitive.
+func WithQueryLogRedactArgs() QueryLoggerOptionFunc {
+	return func(l *QueryLogger) { l.redactArgs = true }
+}
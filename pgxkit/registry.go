@@ -0,0 +1,119 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// UnknownDatabaseError is returned by Registry.Get for names that were not added.
+type UnknownDatabaseError struct {
+	Name string
+}
+
+func (e *UnknownDatabaseError) Error() string { return fmt.Sprintf("unknown database %q", e.Name) }
+
+// Registry manages named Clients, e.g. one per tenant or shard, or an analytics database next to the OLTP one, with
+// options shared by all of them and a common lifecycle.
+type Registry struct {
+	opts []ClientOption
+
+	mu      sync.RWMutex
+	clients map[string]Client
+	names   []string
+}
+
+// NewRegistry creates a registry applying opts to every client, before the options of the client itself. Clients
+// get their name as "database" attribute of the logger and, unless set otherwise, as pool label of the metrics.
+func NewRegistry(opts ...ClientOption) *Registry {
+	return &Registry{opts: opts, clients: make(map[string]Client)}
+}
+
+// Add registers a client for the database at url under name. It is opened with Open, or by the caller if the
+// registry was opened already.
+func (r *Registry) Add(name, url string, opts ...ClientOption) (Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[name]; ok {
+		return nil, fmt.Errorf("database %q already registered", name)
+	}
+
+	opts = append(slices.Clip(r.opts), opts...)
+	opts = append(opts, ClientOptionFunc(func(c *client) {
+		if c.log != nil {
+			c.log = c.log.With("database", name)
+		}
+		if c.metrics != nil && c.metrics.cfg.pool == _defaultMetricsPool {
+			c.metrics.cfg.pool = name
+		}
+	}))
+
+	c := NewClient(url, opts...)
+	r.clients[name] = c
+	r.names = append(r.names, name)
+
+	return c, nil
+}
+
+// Get returns the client registered under name, or an *UnknownDatabaseError.
+func (r *Registry) Get(name string) (Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.clients[name]
+	if !ok {
+		return nil, &UnknownDatabaseError{Name: name}
+	}
+	return c, nil
+}
+
+// Names returns the names of the clients in the order they were added.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.names)
+}
+
+// Open opens all clients in the order they were added. If one fails, those already opened are closed again.
+func (r *Registry) Open(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i, name := range r.names {
+		if err := r.clients[name].Open(ctx); err != nil {
+			for _, opened := range r.names[:i] {
+				r.clients[opened].Close()
+			}
+			return fmt.Errorf("opening database %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes all clients.
+func (r *Registry) Close() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range r.names {
+		r.clients[name].Close()
+	}
+}
+
+// HealthCheck checks all clients and joins the errors of those failing.
+func (r *Registry) HealthCheck(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var errs []error
+	for _, name := range r.names {
+		if err := r.clients[name].HealthCheck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("database %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
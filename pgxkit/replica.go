@@ -0,0 +1,212 @@
+package pgxkit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const _defaultReplicaCheckInterval = 5 * time.Second
+
+// ReplicaLagFunc measures how far a replica is behind the primary.
+type ReplicaLagFunc func(ctx context.Context, replica Queryer) (time.Duration, error)
+
+// ReplicaClient splits reads from writes: SELECT statements run with Query and QueryRow go to the replicas in turn,
+// everything else, including statements returning rows such as INSERT ... RETURNING or WITH queries, and
+// transactions, to the primary. Replicas found unhealthy or lagging by Run are skipped until they recover, and reads
+// fall back to the primary when no replica is available.
+//
+// Replicas lag behind and are read-only: reads that must see preceding writes, and SELECTs with side effects, such as
+// SELECT ... FOR UPDATE or calls of nextval or of functions writing data, must use Primary.
+type ReplicaClient struct {
+	primary  DB
+	replicas []*replica
+	next     atomic.Uint64
+
+	interval time.Duration
+	maxLag   time.Duration
+	lag      ReplicaLagFunc
+	log      *slog.Logger
+}
+
+type replica struct {
+	db      DB
+	healthy atomic.Bool
+}
+
+func NewReplicaClient(primary DB, replicas []DB, opts ...ReplicaOption) *ReplicaClient {
+	c := ReplicaClient{primary: primary, interval: _defaultReplicaCheckInterval, lag: ReplicationLag}
+
+	for _, db := range replicas {
+		r := replica{db: db}
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, &r)
+	}
+
+	for _, opt := range opts {
+		opt.applyToReplicaClient(&c)
+	}
+
+	return &c
+}
+
+// ReplicationLag is the default ReplicaLagFunc, the time since the last transaction replayed by the replica. It
+// overstates the lag while the primary is idle.
+func ReplicationLag(ctx context.Context, replica Queryer) (time.Duration, error) {
+	secs, err := QueryValue[float64](ctx, replica,
+		"SELECT COALESCE(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0)::float8")
+	return time.Duration(secs * float64(time.Second)), err
+}
+
+// Primary returns the primary, for reads that must observe preceding writes.
+func (c *ReplicaClient) Primary() DB { return c.primary }
+
+// Replica returns the next healthy replica, or the primary if there is none.
+func (c *ReplicaClient) Replica() DB {
+	n := len(c.replicas)
+	start := c.next.Add(1)
+
+	for i := range n {
+		if r := c.replicas[(start+uint64(i))%uint64(n)]; r.healthy.Load() {
+			return r.db
+		}
+	}
+
+	return c.primary
+}
+
+// Run checks the replicas at the configured interval until ctx is done.
+func (c *ReplicaClient) Run(ctx context.Context) error {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		for i, r := range c.replicas {
+			c.check(ctx, i, r)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (c *ReplicaClient) check(ctx context.Context, i int, r *replica) {
+	err := ping(ctx, r.db)
+	if err == nil && c.maxLag > 0 {
+		var lag time.Duration
+		if lag, err = c.lag(ctx, r.db); err == nil && lag > c.maxLag {
+			err = fmt.Errorf("replica lags %s behind", lag)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy || c.log == nil {
+		return
+	}
+
+	if healthy {
+		c.log.InfoContext(ctx, "replica recovered", slog.Int("replica", i))
+	} else {
+		c.log.WarnContext(ctx, "replica unavailable",
+			slog.Int("replica", i),
+			slog.Group("error", slog.String("msg", err.Error())),
+		)
+	}
+}
+
+func (c *ReplicaClient) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return c.route(sql).Query(ctx, sql, args...)
+}
+
+func (c *ReplicaClient) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return c.route(sql).QueryRow(ctx, sql, args...)
+}
+
+// route returns a replica for SELECT statements and the primary for all others, which may write.
+func (c *ReplicaClient) route(sql string) DB {
+	if operationName(sql) == "SELECT" {
+		return c.Replica()
+	}
+	return c.primary
+}
+
+func (c *ReplicaClient) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return c.primary.Exec(ctx, sql, args...)
+}
+
+func (c *ReplicaClient) Begin(ctx context.Context) (pgx.Tx, error) { return c.primary.Begin(ctx) }
+
+func (c *ReplicaClient) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return c.primary.BeginTx(ctx, txOptions)
+}
+
+func (c *ReplicaClient) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return c.primary.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (c *ReplicaClient) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.primary.SendBatch(ctx, b)
+}
+
+func (c *ReplicaClient) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return c.primary.Acquire(ctx)
+}
+
+func (c *ReplicaClient) Ping(ctx context.Context) error { return ping(ctx, c.primary) }
+
+// ping pings db if it supports it, as pools and clients do, and runs an empty statement otherwise.
+func ping(ctx context.Context, db DB) error {
+	if p, ok := db.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	return Exec(ctx, db, ";")
+}
+
+// Close closes the primary and all replicas.
+func (c *ReplicaClient) Close() {
+	c.primary.Close()
+	for _, r := range c.replicas {
+		r.db.Close()
+	}
+}
+
+type ReplicaOption interface {
+	applyToReplicaClient(*ReplicaClient)
+}
+
+type ReplicaOptionFunc func(*ReplicaClient)
+
+func (f ReplicaOptionFunc) applyToReplicaClient(c *ReplicaClient) { f(c) }
+
+// WithReplicaCheckInterval sets how often Run checks the replicas. The default is 5s.
+func WithReplicaCheckInterval(d time.Duration) ReplicaOptionFunc {
+	return func(c *ReplicaClient) { c.interval = d }
+}
+
+// WithReplicaMaxLag skips replicas lagging more than d behind the primary, as measured by fn, or ReplicationLag if
+// fn is nil.
+func WithReplicaMaxLag(d time.Duration, fn ReplicaLagFunc) ReplicaOptionFunc {
+	return func(c *ReplicaClient) {
+		c.maxLag = d
+		if fn != nil {
+			c.lag = fn
+		}
+	}
+}
+
+func WithReplicaLogger(log *slog.Logger) ReplicaOptionFunc {
+	return func(c *ReplicaClient) { c.log = log }
+}
@@ -0,0 +1,98 @@
+package pgxkit
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type txContextKey struct{}
+
+// TxToContext returns a copy of ctx carrying tx, which a Runner then uses for all statements run with the context.
+func TxToContext(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by ctx, if any.
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(Tx)
+	return tx, ok
+}
+
+// Runner runs statements in the transaction carried by their context, or on the database otherwise, so repositories
+// take part in a transaction begun by a service without passing a Tx through every call. It implements Queryer,
+// Execer, Copier, BatchSender, Beginner and TxBeginner, so it works with Query, Exec, InTx and the other helpers.
+type Runner struct {
+	db DB
+}
+
+func NewRunner(db DB) *Runner { return &Runner{db: db} }
+
+// InTx runs fn with a context carrying a new transaction, or a savepoint of the transaction ctx already carries.
+func (r *Runner) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.InTxOptions(ctx, TxOptions{}, fn)
+}
+
+// InTxOptions is like InTx, but begins a new transaction with txOptions.
+func (r *Runner) InTxOptions(ctx context.Context, txOptions TxOptions, fn func(ctx context.Context) error) error {
+	var db Beginner = r.db
+	if tx, ok := TxFromContext(ctx); ok {
+		db = tx
+	}
+
+	return InTx(ctx, db, txOptions, func(tx Tx) error {
+		return fn(TxToContext(ctx, tx))
+	})
+}
+
+func (r *Runner) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return r.db.Begin(ctx)
+}
+
+// BeginTx begins a transaction with txOptions, or a savepoint of the transaction ctx carries, which inherits the
+// settings of that transaction.
+func (r *Runner) BeginTx(ctx context.Context, txOptions TxOptions) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return r.db.BeginTx(ctx, txOptions)
+}
+
+func (r *Runner) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Query(ctx, sql, args...)
+	}
+	return r.db.Query(ctx, sql, args...)
+}
+
+func (r *Runner) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+	return r.db.QueryRow(ctx, sql, args...)
+}
+
+func (r *Runner) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Exec(ctx, sql, args...)
+	}
+	return r.db.Exec(ctx, sql, args...)
+}
+
+func (r *Runner) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+	return r.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (r *Runner) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.SendBatch(ctx, b)
+	}
+	return r.db.SendBatch(ctx, b)
+}
@@ -0,0 +1,128 @@
+package pgxkit
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// slowQueryLogger is a pgx tracer logging statements that take longer than threshold at warn level.
+type slowQueryLogger struct {
+	log       *slog.Logger
+	threshold time.Duration
+}
+
+type slowQueryKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	start time.Time
+}
+
+func (l *slowQueryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryKey{}, slowQueryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (l *slowQueryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(slowQueryKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+
+	d := time.Since(start.start)
+	if d < l.threshold {
+		return
+	}
+
+	sql := NormalizeSQL(start.sql)
+	l.log.LogAttrs(ctx, slog.LevelWarn, "slow query",
+		slog.String("fingerprint", fingerprint(sql)),
+		slog.String("sql", sql),
+		slog.Duration("duration", d),
+		slog.Duration("threshold", l.threshold),
+		slog.Int64("rows", data.CommandTag.RowsAffected()),
+	)
+}
+
+// NormalizeSQL replaces literals in sql with "?", removes comments and collapses whitespace, so statements that only
+// differ in their values normalize to the same text.
+func NormalizeSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case c == '\'':
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+		case isDigit(c) && !precededByWord(sql, i):
+			for i+1 < len(sql) && (isDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// precededByWord reports whether the digit at i is part of an identifier or a $n placeholder.
+func precededByWord(sql string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	c := sql[i-1]
+	return c == '_' || c == '$' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+func fingerprint(normalized string) string {
+	h := fnv.New64a()
+	h.Write([]byte(normalized))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
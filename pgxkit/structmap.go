@@ -0,0 +1,152 @@
+package pgxkit
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// structField is a column of a struct type mapped by its db tag.
+type structField struct {
+	column    string
+	index     []int
+	generated bool
+}
+
+var structFieldsCache sync.Map
+
+var errNilRow = errors.New("row is a nil pointer")
+
+// structFields returns the columns of struct type t like pgx.RowToStructByName maps them: the name in the db tag, or
+// the snake-cased field name without one. Fields tagged "-" are skipped and embedded structs without a tag are
+// flattened. The tag option "generated", as in `db:"id,generated"`, marks columns filled by the database, which are
+// left out of inserts. Pointer types are mapped like the struct they point to.
+func structFields(t reflect.Type) []structField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.([]structField)
+	}
+
+	fields := appendStructFields(nil, t, nil)
+	structFieldsCache.Store(t, fields)
+
+	return fields
+}
+
+func appendStructFields(fields []structField, t reflect.Type, index []int) []structField {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+
+		tag, hasTag := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		if sf.Anonymous && !hasTag && sf.Type.Kind() == reflect.Struct {
+			fields = appendStructFields(fields, sf.Type, idx)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+
+		fields = append(fields, structField{
+			column:    name,
+			index:     idx,
+			generated: hasOption(opts, "generated"),
+		})
+	}
+
+	return fields
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeCase converts a Go field name such as "CreatedAt" or "UserID" to "created_at" or "user_id".
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// insertFields returns the columns of T written by inserts, those not marked generated.
+func insertFields[T any]() []structField {
+	var fields []structField
+	for _, f := range structFields(reflect.TypeFor[T]()) {
+		if !f.generated {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func columnNames(fields []structField) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.column
+	}
+	return names
+}
+
+// fieldValues returns the values of the fields of the struct v, or the struct v points to.
+func fieldValues(v reflect.Value, fields []structField) ([]any, error) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, errNilRow
+		}
+		v = v.Elem()
+	}
+
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		values[i] = v.FieldByIndex(f.index).Interface()
+	}
+	return values, nil
+}
+
+// tableIdentifier splits a possibly schema-qualified table name.
+func tableIdentifier(table string) pgx.Identifier {
+	return pgx.Identifier(strings.Split(table, "."))
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = pgx.Identifier{c}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
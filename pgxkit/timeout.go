@@ -0,0 +1,40 @@
+package pgxkit
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// WithStatementTimeout runs fn like InTx with a statement_timeout of timeout, so expensive queries get a tighter limit
+// than the default of the pool and fail with ErrTimeout when the server cancels them. The limit applies to each
+// statement, not to fn as a whole; bound the total with a deadline on ctx. In a savepoint, the previous
+// statement_timeout of the enclosing transaction is restored afterwards.
+func WithStatementTimeout(ctx context.Context, db Beginner, timeout time.Duration, fn func(Tx) error) error {
+	// A timeout of 0 would disable the limit.
+	ms := strconv.FormatInt(max(timeout.Milliseconds(), 1), 10) + "ms"
+	_, nested := db.(Tx)
+
+	return InTx(ctx, db, TxOptions{}, func(tx Tx) error {
+		var prev string
+		if nested {
+			var err error
+			if prev, err = QueryValue[string](ctx, tx, "SELECT current_setting('statement_timeout')"); err != nil {
+				return err
+			}
+		}
+
+		if err := Exec(ctx, tx, "SELECT set_config('statement_timeout', $1, true)", ms); err != nil {
+			return err
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		if nested {
+			return Exec(ctx, tx, "SELECT set_config('statement_timeout', $1, true)", prev)
+		}
+		return nil
+	})
+}
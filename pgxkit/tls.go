@@ -0,0 +1,161 @@
+package pgxkit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TLSMode is how the server certificate is verified, like the verifying values of the sslmode URL parameter.
+type TLSMode int
+
+const (
+	// TLSVerifyFull verifies the certificate chain and that the certificate matches the host.
+	TLSVerifyFull TLSMode = iota
+	// TLSVerifyCA verifies the certificate chain only.
+	TLSVerifyCA
+	// TLSRequire encrypts the connection without verifying the certificate.
+	TLSRequire
+)
+
+// WithPGTLS connects with TLS, verifying the server against the CA bundle in caFile, or the system roots if empty,
+// and authenticating with the client certificate in ceFile and keyFile if given. It replaces the sslmode and related
+// URL parameters. The files are read when the client is opened.
+func WithPGTLS(caFile, ceFile, keyFile string, mode TLSMode) ClientOptionFunc {
+	return pgTLSFromFiles(os.ReadFile, caFile, ceFile, keyFile, mode)
+}
+
+// WithPGTLSFromFS is WithPGTLS reading from fsys, e.g. an embed.FS.
+func WithPGTLSFromFS(fsys fs.FS, caFile, ceFile, keyFile string, mode TLSMode) ClientOptionFunc {
+	readFile := func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) }
+	return pgTLSFromFiles(readFile, caFile, ceFile, keyFile, mode)
+}
+
+// WithPGTLSFromPEM is WithPGTLS taking PEM encoded bytes, for certificates held in memory.
+func WithPGTLSFromPEM(caPEM, certPEM, keyPEM []byte, mode TLSMode) ClientOptionFunc {
+	return WithPGTLSConfig(func() (*tls.Config, error) { return newPGTLSConfig(caPEM, certPEM, keyPEM, mode) })
+}
+
+// WithPGTLSConfig connects with the TLS configuration loaded through fn when the client is opened. ServerName is set
+// to the host connected to unless InsecureSkipVerify is set.
+func WithPGTLSConfig(fn func() (*tls.Config, error)) ClientOptionFunc {
+	return func(c *client) { c.loadTLS = fn }
+}
+
+func pgTLSFromFiles(readFile func(string) ([]byte, error), caFile, ceFile, keyFile string, mode TLSMode) ClientOptionFunc {
+	return WithPGTLSConfig(func() (*tls.Config, error) {
+		var ca, ce, key []byte
+		var err error
+
+		if caFile != "" {
+			if ca, err = readFile(caFile); err != nil {
+				return nil, fmt.Errorf("loading TLS CA bundle from %s: %w", caFile, err)
+			}
+		}
+
+		if ceFile != "" || keyFile != "" {
+			if ce, err = readFile(ceFile); err != nil {
+				return nil, fmt.Errorf("loading TLS certificate from %s: %w", ceFile, err)
+			}
+			if key, err = readFile(keyFile); err != nil {
+				return nil, fmt.Errorf("loading TLS key from %s: %w", keyFile, err)
+			}
+		}
+
+		return newPGTLSConfig(ca, ce, key, mode)
+	})
+}
+
+func newPGTLSConfig(caPEM, certPEM, keyPEM []byte, mode TLSMode) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(certPEM) > 0 || len(keyPEM) > 0 {
+		ce, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS key pair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{ce}
+	}
+
+	if len(caPEM) > 0 {
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("loading TLS CA bundle: no certificates found in PEM")
+		}
+	}
+
+	switch mode {
+	case TLSVerifyFull:
+	case TLSVerifyCA:
+		// Without hostname verification, the chain is verified by hand, as crypto/tls only skips both together.
+		roots := cfg.RootCAs
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(raw, roots)
+		}
+	case TLSRequire:
+		cfg.InsecureSkipVerify = true
+	default:
+		return nil, fmt.Errorf("invalid TLS mode: %d", mode)
+	}
+
+	return cfg, nil
+}
+
+func verifyChain(raw [][]byte, roots *x509.CertPool) error {
+	certs := make([]*x509.Certificate, len(raw))
+	for i, b := range raw {
+		c, err := x509.ParseCertificate(b)
+		if err != nil {
+			return err
+		}
+		certs[i] = c
+	}
+
+	if len(certs) == 0 {
+		return errors.New("server sent no certificate")
+	}
+
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, c := range certs[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// applyTLS sets tlsConfig for the host and every fallback host, dropping the plaintext fallbacks sslmode=prefer adds.
+func applyTLS(cc *pgconn.Config, tlsConfig *tls.Config) {
+	withHost := func(host string) *tls.Config {
+		cfg := tlsConfig.Clone()
+		if !cfg.InsecureSkipVerify && cfg.ServerName == "" {
+			cfg.ServerName = host
+		}
+		return cfg
+	}
+
+	cc.TLSConfig = withHost(cc.Host)
+
+	type addr struct {
+		host string
+		port uint16
+	}
+	seen := map[addr]bool{{cc.Host, cc.Port}: true}
+
+	fallbacks := cc.Fallbacks[:0]
+	for _, fb := range cc.Fallbacks {
+		if seen[addr{fb.Host, fb.Port}] {
+			continue
+		}
+		seen[addr{fb.Host, fb.Port}] = true
+		fb.TLSConfig = withHost(fb.Host)
+		fallbacks = append(fallbacks, fb)
+	}
+	cc.Fallbacks = fallbacks
+}
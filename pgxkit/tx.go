@@ -0,0 +1,181 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithTx runs fn in a transaction begun on db. The transaction is committed if fn returns nil and rolled back if it
+// returns an error or panics. Errors are mapped like those of Query, so ErrNotFound and ErrAlreadyExists can be
+// checked for.
+func WithTx(ctx context.Context, db Beginner, fn func(Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", mapErr(err))
+	}
+
+	return runTx(ctx, tx, fn)
+}
+
+// WithTxOptions is like WithTx, but begins the transaction with txOptions, e.g. a read-only Serializable one.
+func WithTxOptions(ctx context.Context, db TxBeginner, txOptions TxOptions, fn func(Tx) error) error {
+	tx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", mapErr(err))
+	}
+
+	return runTx(ctx, tx, fn)
+}
+
+// InTx runs fn in a savepoint if db is a Tx, so the work of fn is undone on failure while the enclosing
+// transaction continues, and in a new transaction begun with txOptions otherwise. Repository methods using it compose
+// into larger transactions without knowing whether they are nested. txOptions do not apply to savepoints, which
+// inherit the settings of the enclosing transaction. Options given for a db that can only Begin are an error rather
+// than silently dropped.
+func InTx(ctx context.Context, db Beginner, txOptions TxOptions, fn func(Tx) error) error {
+	if _, nested := db.(Tx); !nested {
+		if b, ok := db.(TxBeginner); ok {
+			return WithTxOptions(ctx, b, txOptions, fn)
+		}
+		if txOptions != (TxOptions{}) {
+			return fmt.Errorf("beginning transaction: %T does not support transaction options", db)
+		}
+	}
+
+	// Begin on a pgx.Tx creates a savepoint, which Commit releases and Rollback rolls back to.
+	return WithTx(ctx, db, fn)
+}
+
+func runTx(ctx context.Context, tx pgx.Tx, fn func(Tx) error) error {
+	defer func() {
+		if rec := recover(); rec != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(rec)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return errors.Join(mapErr(err), rollback(ctx, tx))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", mapErr(err))
+	}
+
+	return nil
+}
+
+// rollback rolls tx back even if ctx is done, so the connection is released cleanly. A transaction already closed
+// by fn is not an error.
+func rollback(ctx context.Context, tx pgx.Tx) error {
+	if err := tx.Rollback(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return fmt.Errorf("rolling back transaction: %w", err)
+	}
+	return nil
+}
+
+const (
+	_defaultTxMaxAttempts = 5
+	_defaultTxBaseDelay   = 10 * time.Millisecond
+	_defaultTxMaxDelay    = time.Second
+)
+
+type txRetry struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	log         *slog.Logger
+}
+
+// WithTxRetry is like WithTxOptions, but runs fn again in a new transaction when it fails with a serialization
+// failure or a deadlock, which Serializable and RepeatableRead transactions must expect under contention. Retries
+// wait with capped exponential backoff and full jitter. fn must therefore be safe to run several times.
+func WithTxRetry(ctx context.Context, db TxBeginner, txOptions TxOptions, fn func(Tx) error, opts ...TxRetryOption) error {
+	r := txRetry{maxAttempts: _defaultTxMaxAttempts, baseDelay: _defaultTxBaseDelay, maxDelay: _defaultTxMaxDelay}
+	for _, opt := range opts {
+		opt.applyToTxRetry(&r)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := WithTxOptions(ctx, db, txOptions, fn)
+		if err == nil || !retryableTx(err) || attempt >= r.maxAttempts {
+			if err != nil && attempt > 1 && r.log != nil {
+				r.log.WarnContext(ctx, "transaction failed after retries",
+					slog.Int("attempts", attempt),
+					slog.Group("error", slog.String("msg", err.Error())),
+				)
+			}
+			return err
+		}
+
+		delay := r.backoff(attempt)
+		if r.log != nil {
+			r.log.InfoContext(ctx, "retrying transaction",
+				slog.Int("attempt", attempt),
+				slog.Duration("delay", delay),
+				slog.Group("error", slog.String("msg", err.Error())),
+			)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+func (r *txRetry) backoff(attempt int) time.Duration {
+	if r.maxDelay <= 0 {
+		return 0
+	}
+	d := r.baseDelay << (attempt - 1)
+	if d <= 0 || d > r.maxDelay {
+		d = r.maxDelay
+	}
+	return rand.N(d) + 1
+}
+
+// retryableTx reports whether err is a serialization failure or deadlock, after which the transaction may succeed
+// when run again.
+func retryableTx(err error) bool {
+	var pgerr *pgconn.PgError
+	if !errors.As(err, &pgerr) {
+		return false
+	}
+	return pgerr.Code == pgerrcode.SerializationFailure || pgerr.Code == pgerrcode.DeadlockDetected
+}
+
+type TxRetryOption interface {
+	applyToTxRetry(*txRetry)
+}
+
+type TxRetryOptionFunc func(*txRetry)
+
+func (f TxRetryOptionFunc) applyToTxRetry(r *txRetry) { f(r) }
+
+// WithTxMaxAttempts sets how often fn is run at most, including the first attempt. The default is 5.
+func WithTxMaxAttempts(n int) TxRetryOptionFunc {
+	return func(r *txRetry) { r.maxAttempts = max(n, 1) }
+}
+
+// WithTxBackoff sets the delay before the first retry, doubled on every further retry up to maxDelay. The defaults
+// are 10ms and 1s.
+func WithTxBackoff(base, maxDelay time.Duration) TxRetryOptionFunc {
+	return func(r *txRetry) { r.baseDelay, r.maxDelay = base, maxDelay }
+}
+
+// WithTxLogger logs every retry and transactions that still fail after being retried.
+func WithTxLogger(log *slog.Logger) TxRetryOptionFunc {
+	return func(r *txRetry) { r.log = log }
+}
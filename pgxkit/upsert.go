@@ -0,0 +1,113 @@
+package pgxkit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type upsertConfig struct {
+	conflict  []string
+	update    []string
+	doNothing bool
+}
+
+// Upsert inserts row into table, updating the existing row instead if one conflicts on the columns given with
+// WithConflictColumns. Columns are derived from T like for InsertRows. By default all inserted columns except the
+// conflict columns are updated.
+func Upsert[T any](ctx context.Context, db Execer, table string, row T, opts ...UpsertOption) error {
+	_, err := UpsertRows(ctx, db, table, []T{row}, opts...)
+	return err
+}
+
+// UpsertRows is Upsert for several rows, split into statements within the parameter limit. It returns the number of
+// rows inserted or updated. Rows must not conflict with each other, which Postgres rejects in a single statement.
+func UpsertRows[T any](ctx context.Context, db Execer, table string, rows []T, opts ...UpsertOption) (int64, error) {
+	var cfg upsertConfig
+	for _, opt := range opts {
+		opt.applyToUpsert(&cfg)
+	}
+
+	if len(cfg.conflict) == 0 {
+		return 0, errors.New("upsert requires conflict columns")
+	}
+
+	fields := insertFields[T]()
+	if len(fields) == 0 || len(rows) == 0 {
+		return 0, nil
+	}
+
+	columns := columnNames(fields)
+	prefix := "INSERT INTO " + tableIdentifier(table).Sanitize() + " (" + quoteColumns(columns) + ") VALUES "
+	suffix := " ON CONFLICT (" + quoteColumns(cfg.conflict) + ") " + cfg.action(columns)
+
+	chunk := _maxParams / len(fields)
+	var total int64
+
+	for start := 0; start < len(rows); start += chunk {
+		end := min(start+chunk, len(rows))
+
+		sql, args, err := valuesSQL(rows[start:end], fields)
+		if err != nil {
+			return total, err
+		}
+
+		tag, err := db.Exec(ctx, prefix+sql+suffix, args...)
+		if err != nil {
+			return total, mapErr(err)
+		}
+		total += tag.RowsAffected()
+	}
+
+	return total, nil
+}
+
+// action returns the DO UPDATE or DO NOTHING clause.
+func (c *upsertConfig) action(columns []string) string {
+	update := c.update
+	if update == nil {
+		for _, col := range columns {
+			if !slices.Contains(c.conflict, col) {
+				update = append(update, col)
+			}
+		}
+	}
+
+	if c.doNothing || len(update) == 0 {
+		return "DO NOTHING"
+	}
+
+	set := make([]string, len(update))
+	for i, col := range update {
+		ident := pgx.Identifier{col}.Sanitize()
+		set[i] = ident + " = EXCLUDED." + ident
+	}
+
+	return "DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+type UpsertOption interface {
+	applyToUpsert(*upsertConfig)
+}
+
+type UpsertOptionFunc func(*upsertConfig)
+
+func (f UpsertOptionFunc) applyToUpsert(c *upsertConfig) { f(c) }
+
+// WithConflictColumns sets the columns of the unique constraint or index that detects existing rows.
+func WithConflictColumns(columns ...string) UpsertOptionFunc {
+	return func(c *upsertConfig) { c.conflict = columns }
+}
+
+// WithUpdateColumns restricts the columns updated on conflict, e.g. to keep created_at of existing rows.
+func WithUpdateColumns(columns ...string) UpsertOptionFunc {
+	return func(c *upsertConfig) { c.update = columns }
+}
+
+// WithDoNothing keeps existing rows unchanged on conflict.
+func WithDoNothing() UpsertOptionFunc {
+	return func(c *upsertConfig) { c.doNothing = true }
+}